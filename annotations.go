@@ -0,0 +1,545 @@
+package radareutil
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	annotationNameInvalidChars = " \t\r\n;@|`\"'>#~*"
+
+	// autoFunctionNamePrefix is the prefix of function names
+	// generated by radare2's analysis.
+	autoFunctionNamePrefix = "fcn."
+)
+
+type AnnotationKind string
+
+func (o AnnotationKind) String() string {
+	return string(o)
+}
+
+const (
+	CommentAnnotation      AnnotationKind = "comment"
+	FlagAnnotation         AnnotationKind = "flag"
+	FunctionNameAnnotation AnnotationKind = "function_name"
+)
+
+// Comment is a comment as reported by 'CCj'.
+type Comment struct {
+	Addr uint64 `json:"addr"`
+	Text string `json:"text"`
+}
+
+type rawComment struct {
+	Offset uint64 `json:"offset"`
+	Name   string `json:"name"`
+}
+
+// Flag is a flag as reported by 'fj'.
+type Flag struct {
+	Name  string `json:"name"`
+	Addr  uint64 `json:"addr"`
+	Size  uint64 `json:"size"`
+	Space string `json:"space,omitempty"`
+}
+
+type rawFlag struct {
+	Name   string `json:"name"`
+	Offset uint64 `json:"offset"`
+	Size   uint64 `json:"size"`
+}
+
+// FlagSpace is a flag space as reported by 'fsj'.
+type FlagSpace struct {
+	Name     string `json:"name"`
+	Count    int    `json:"count"`
+	Selected bool   `json:"selected"`
+}
+
+// FunctionName is the name of the function at an address.
+type FunctionName struct {
+	Addr uint64 `json:"addr"`
+	Name string `json:"name"`
+}
+
+// Comments returns the comments in the session ('CCj').
+func Comments(api Api) ([]Comment, error) {
+	var raws []rawComment
+	err := api.ExecuteToJson("CCj", &raws)
+	if err != nil {
+		return nil, err
+	}
+
+	comments := make([]Comment, len(raws))
+	for i, raw := range raws {
+		text := raw.Name
+		// Older versions of radare2 base64 encode comments.
+		if strings.HasPrefix(text, "base64:") {
+			decoded, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(text, "base64:"))
+			if err == nil {
+				text = string(decoded)
+			}
+		}
+
+		comments[i] = Comment{
+			Addr: raw.Offset,
+			Text: text,
+		}
+	}
+
+	return comments, nil
+}
+
+// SetComment sets the comment at addr, replacing any existing
+// comment ('CCu'). The text is base64 encoded when it is passed to
+// radare2, which allows it to contain any character, including
+// new lines.
+func SetComment(api Api, addr uint64, text string) error {
+	encoded := base64.StdEncoding.EncodeToString([]byte(text))
+
+	_, err := api.Execute(fmt.Sprintf("CCu base64:%s @ 0x%x", encoded, addr))
+	return err
+}
+
+// DeleteComment deletes the comment at addr ('CC-').
+func DeleteComment(api Api, addr uint64) error {
+	_, err := api.Execute(fmt.Sprintf("CC- @ 0x%x", addr))
+	return err
+}
+
+// Flags returns the flags in the currently selected flag space ('fj').
+// The Space field of the returned flags is not set.
+func Flags(api Api) ([]Flag, error) {
+	var raws []rawFlag
+	err := api.ExecuteToJson("fj", &raws)
+	if err != nil {
+		return nil, err
+	}
+
+	flags := make([]Flag, len(raws))
+	for i, raw := range raws {
+		flags[i] = Flag{
+			Name: raw.Name,
+			Addr: raw.Offset,
+			Size: raw.Size,
+		}
+	}
+
+	return flags, nil
+}
+
+// AllFlags returns the flags in every flag space, including flags that
+// are not in a flag space. The Space field of such flags is not set.
+func AllFlags(api Api) ([]Flag, error) {
+	var flags []Flag
+	err := withFlagSpace(api, "", func() error {
+		var err error
+		flags, err = Flags(api)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	spaces, err := FlagSpaces(api)
+	if err != nil {
+		return nil, err
+	}
+
+	// Flag names are unique across flag spaces.
+	flagSpaces := make(map[string]string)
+	for _, space := range spaces {
+		err := withFlagSpace(api, space.Name, func() error {
+			spaceFlags, err := Flags(api)
+			if err != nil {
+				return err
+			}
+
+			for _, flag := range spaceFlags {
+				flagSpaces[flag.Name] = space.Name
+			}
+
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for i := range flags {
+		flags[i].Space = flagSpaces[flags[i].Name]
+	}
+
+	return flags, nil
+}
+
+// SetFlag creates or moves a flag. If flag.Space is non-empty, the flag
+// is created in that flag space ('f name size @ addr').
+func SetFlag(api Api, flag Flag) error {
+	err := validateAnnotationName(flag.Name)
+	if err != nil {
+		return err
+	}
+
+	size := flag.Size
+	if size == 0 {
+		size = 1
+	}
+
+	command := fmt.Sprintf("f %s %d @ 0x%x", flag.Name, size, flag.Addr)
+
+	if flag.Space == "" {
+		_, err = api.Execute(command)
+		return err
+	}
+
+	return withFlagSpace(api, flag.Space, func() error {
+		_, err := api.Execute(command)
+		return err
+	})
+}
+
+// DeleteFlag deletes the named flag ('f-').
+func DeleteFlag(api Api, name string) error {
+	err := validateAnnotationName(name)
+	if err != nil {
+		return err
+	}
+
+	_, err = api.Execute("f-" + name)
+	return err
+}
+
+// FlagSpaces returns the flag spaces in the session ('fsj').
+func FlagSpaces(api Api) ([]FlagSpace, error) {
+	var spaces []FlagSpace
+	err := api.ExecuteToJson("fsj", &spaces)
+	if err != nil {
+		return nil, err
+	}
+
+	return spaces, nil
+}
+
+// SelectFlagSpace selects the named flag space, creating it if it does
+// not exist ('fs'). An empty name selects all flag spaces.
+func SelectFlagSpace(api Api, name string) error {
+	if name == "" {
+		_, err := api.Execute("fs *")
+		return err
+	}
+
+	err := validateAnnotationName(name)
+	if err != nil {
+		return err
+	}
+
+	_, err = api.Execute("fs " + name)
+	return err
+}
+
+// withFlagSpace selects the named flag space, calls fn, and then
+// selects the flag space that was originally selected.
+func withFlagSpace(api Api, name string, fn func() error) error {
+	spaces, err := FlagSpaces(api)
+	if err != nil {
+		return err
+	}
+
+	original := ""
+	for _, space := range spaces {
+		if space.Selected {
+			original = space.Name
+			break
+		}
+	}
+
+	err = SelectFlagSpace(api, name)
+	if err != nil {
+		return err
+	}
+
+	err = fn()
+
+	restoreErr := SelectFlagSpace(api, original)
+	if err == nil && restoreErr != nil {
+		return fmt.Errorf("failed to restore flag space - %s", restoreErr.Error())
+	}
+
+	return err
+}
+
+// FunctionNames returns the name of every analyzed function ('aflj').
+func FunctionNames(api Api) ([]FunctionName, error) {
+	functions, err := Functions(api)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]FunctionName, len(functions))
+	for i, function := range functions {
+		names[i] = FunctionName{
+			Addr: function.Addr,
+			Name: function.Name,
+		}
+	}
+
+	return names, nil
+}
+
+// RenameFunction renames the function at addr ('afn').
+func RenameFunction(api Api, addr uint64, name string) error {
+	err := validateAnnotationName(name)
+	if err != nil {
+		return err
+	}
+
+	_, err = api.Execute(fmt.Sprintf("afn %s @ 0x%x", name, addr))
+	return err
+}
+
+func validateAnnotationName(name string) error {
+	if name == "" {
+		return errors.New("name is empty")
+	}
+
+	if strings.ContainsAny(name, annotationNameInvalidChars) {
+		return fmt.Errorf("name '%s' contains one of the characters '%s'", name, annotationNameInvalidChars)
+	}
+
+	return nil
+}
+
+// Annotations are the comments, flags, and function names of a session.
+// They can be exported from one session and imported into another.
+type Annotations struct {
+	Comments      []Comment      `json:"comments"`
+	Flags         []Flag         `json:"flags"`
+	FunctionNames []FunctionName `json:"function_names"`
+}
+
+// Encode writes the annotations to w as JSON.
+func (o *Annotations) Encode(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(o)
+}
+
+func (o *Annotations) validate() error {
+	for _, flag := range o.Flags {
+		err := validateAnnotationName(flag.Name)
+		if err != nil {
+			return fmt.Errorf("invalid flag name - %s", err.Error())
+		}
+
+		if flag.Space != "" {
+			err = validateAnnotationName(flag.Space)
+			if err != nil {
+				return fmt.Errorf("invalid flag space name - %s", err.Error())
+			}
+		}
+	}
+
+	for _, functionName := range o.FunctionNames {
+		err := validateAnnotationName(functionName.Name)
+		if err != nil {
+			return fmt.Errorf("invalid function name - %s", err.Error())
+		}
+	}
+
+	return nil
+}
+
+// DecodeAnnotations decodes annotations written by Annotations.Encode.
+func DecodeAnnotations(r io.Reader) (*Annotations, error) {
+	var annotations Annotations
+	err := json.NewDecoder(r).Decode(&annotations)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode annotations - %s", err.Error())
+	}
+
+	return &annotations, nil
+}
+
+// AnnotationConflict is an annotation that could not be imported
+// without replacing an existing, different annotation.
+type AnnotationConflict struct {
+	Kind AnnotationKind `json:"kind"`
+	Addr uint64         `json:"addr"`
+
+	// Current is the existing annotation in the session. It is empty
+	// if a function name could not be imported because there is no
+	// function at Addr.
+	Current string `json:"current"`
+
+	// Incoming is the annotation that was being imported.
+	Incoming string `json:"incoming"`
+
+	// Replaced is true if the existing annotation was replaced.
+	Replaced bool `json:"replaced"`
+}
+
+// ExportAnnotations returns the comments, flags, and function names
+// of the session.
+func ExportAnnotations(api Api) (*Annotations, error) {
+	comments, err := Comments(api)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get comments - %s", err.Error())
+	}
+
+	flags, err := AllFlags(api)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get flags - %s", err.Error())
+	}
+
+	functionNames, err := FunctionNames(api)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get function names - %s", err.Error())
+	}
+
+	return &Annotations{
+		Comments:      comments,
+		Flags:         flags,
+		FunctionNames: functionNames,
+	}, nil
+}
+
+// ImportAnnotations merges annotations into the session. An annotation
+// conflicts with the session when:
+//
+//   - A comment already exists at the same address with different text
+//   - A flag with the same name already exists at a different address
+//   - A function already exists at the same address with a different
+//     name that was not generated by radare2, or there is no function
+//     at the address
+//
+// Conflicting annotations are only imported if replace is true. All
+// conflicts are returned, whether or not they were replaced.
+func ImportAnnotations(api Api, annotations *Annotations, replace bool) ([]AnnotationConflict, error) {
+	// Names are validated before anything is imported so that an
+	// invalid annotation does not leave the import half finished.
+	err := annotations.validate()
+	if err != nil {
+		return nil, err
+	}
+
+	current, err := ExportAnnotations(api)
+	if err != nil {
+		return nil, err
+	}
+
+	var conflicts []AnnotationConflict
+
+	comments := make(map[uint64]string)
+	for _, comment := range current.Comments {
+		comments[comment.Addr] = comment.Text
+	}
+
+	for _, comment := range annotations.Comments {
+		existing, exists := comments[comment.Addr]
+		if exists && existing == comment.Text {
+			continue
+		}
+
+		if exists {
+			conflicts = append(conflicts, AnnotationConflict{
+				Kind:     CommentAnnotation,
+				Addr:     comment.Addr,
+				Current:  existing,
+				Incoming: comment.Text,
+				Replaced: replace,
+			})
+			if !replace {
+				continue
+			}
+		}
+
+		err := SetComment(api, comment.Addr, comment.Text)
+		if err != nil {
+			return conflicts, fmt.Errorf("failed to set comment at 0x%x - %s", comment.Addr, err.Error())
+		}
+	}
+
+	flags := make(map[string]Flag)
+	for _, flag := range current.Flags {
+		flags[flag.Name] = flag
+	}
+
+	for _, flag := range annotations.Flags {
+		existing, exists := flags[flag.Name]
+		if exists && existing.Addr == flag.Addr {
+			continue
+		}
+
+		if exists {
+			conflicts = append(conflicts, AnnotationConflict{
+				Kind:     FlagAnnotation,
+				Addr:     flag.Addr,
+				Current:  fmt.Sprintf("%s @ 0x%x", existing.Name, existing.Addr),
+				Incoming: fmt.Sprintf("%s @ 0x%x", flag.Name, flag.Addr),
+				Replaced: replace,
+			})
+			if !replace {
+				continue
+			}
+		}
+
+		err := SetFlag(api, flag)
+		if err != nil {
+			return conflicts, fmt.Errorf("failed to set flag '%s' - %s", flag.Name, err.Error())
+		}
+	}
+
+	functionNames := make(map[uint64]string)
+	for _, functionName := range current.FunctionNames {
+		functionNames[functionName.Addr] = functionName.Name
+	}
+
+	for _, functionName := range annotations.FunctionNames {
+		existing, exists := functionNames[functionName.Addr]
+		if existing == functionName.Name {
+			continue
+		}
+
+		if !exists {
+			conflicts = append(conflicts, AnnotationConflict{
+				Kind:     FunctionNameAnnotation,
+				Addr:     functionName.Addr,
+				Incoming: functionName.Name,
+			})
+			continue
+		}
+
+		if strings.HasPrefix(existing, autoFunctionNamePrefix) {
+			err := RenameFunction(api, functionName.Addr, functionName.Name)
+			if err != nil {
+				return conflicts, fmt.Errorf("failed to rename function at 0x%x - %s", functionName.Addr, err.Error())
+			}
+			continue
+		}
+
+		conflicts = append(conflicts, AnnotationConflict{
+			Kind:     FunctionNameAnnotation,
+			Addr:     functionName.Addr,
+			Current:  existing,
+			Incoming: functionName.Name,
+			Replaced: replace,
+		})
+		if !replace {
+			continue
+		}
+
+		err := RenameFunction(api, functionName.Addr, functionName.Name)
+		if err != nil {
+			return conflicts, fmt.Errorf("failed to rename function at 0x%x - %s", functionName.Addr, err.Error())
+		}
+	}
+
+	return conflicts, nil
+}
@@ -0,0 +1,108 @@
+package radareutil
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// fakeRadare2Annotations is a fake radare2 with flags in two flag spaces
+// and one flag that is not in a flag space. It stores a single comment.
+const fakeRadare2Annotations = `#!/bin/sh
+selected='*'
+comment=
+printf '\000'
+while IFS= read -r line; do
+	case "$line" in
+	"fs "*)
+		selected=${line#fs }
+		;;
+	fsj)
+		printf '[{"name":"symbols","count":1,"selected":%s},{"name":"strings","count":1,"selected":%s}]\n' \
+			"$([ "$selected" = symbols ] && echo true || echo false)" \
+			"$([ "$selected" = strings ] && echo true || echo false)"
+		;;
+	fj)
+		case "$selected" in
+		symbols)
+			echo '[{"name":"main","offset":4096,"size":16}]'
+			;;
+		strings)
+			echo '[{"name":"str.hello","offset":8192,"size":6}]'
+			;;
+		*)
+			echo '[{"name":"main","offset":4096,"size":16},{"name":"str.hello","offset":8192,"size":6},{"name":"spaceless","offset":12288,"size":1}]'
+			;;
+		esac
+		;;
+	"CCu base64:"*)
+		comment=${line#CCu }
+		comment=${comment% @ *}
+		commentAddr=$((${line##* @ }))
+		;;
+	CCj)
+		printf '[{"offset":%d,"name":"%s"}]\n' "$commentAddr" "$comment"
+		;;
+	esac
+	printf '\000'
+done
+`
+
+func TestAllFlags(t *testing.T) {
+	api, cleanup := startFakeCliApi(t, fakeRadare2Annotations, 10*time.Second)
+	defer cleanup()
+
+	flags, err := AllFlags(api)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []Flag{
+		{Name: "main", Addr: 0x1000, Size: 16, Space: "symbols"},
+		{Name: "str.hello", Addr: 0x2000, Size: 6, Space: "strings"},
+		{Name: "spaceless", Addr: 0x3000, Size: 1},
+	}
+	if !reflect.DeepEqual(flags, expected) {
+		t.Errorf("flags are %+v - expected %+v", flags, expected)
+	}
+}
+
+func TestSetCommentMultipleLines(t *testing.T) {
+	api, cleanup := startFakeCliApi(t, fakeRadare2Annotations, 10*time.Second)
+	defer cleanup()
+
+	text := "first line\nsecond line; with `special` \"characters\""
+
+	err := SetComment(api, 0x1000, text)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	comments, err := Comments(api)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []Comment{
+		{Addr: 0x1000, Text: text},
+	}
+	if !reflect.DeepEqual(comments, expected) {
+		t.Errorf("comments are %+v - expected %+v", comments, expected)
+	}
+}
+
+func TestImportAnnotationsInvalidName(t *testing.T) {
+	annotations := &Annotations{
+		Comments: []Comment{
+			{Addr: 0x1000, Text: "imported"},
+		},
+		FunctionNames: []FunctionName{
+			{Addr: 0x1000, Name: "bad name"},
+		},
+	}
+
+	_, err := ImportAnnotations(nil, annotations, false)
+	if err == nil {
+		t.Fatal("annotations with an invalid function name were imported")
+	}
+}
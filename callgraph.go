@@ -0,0 +1,249 @@
+package radareutil
+
+import (
+	"sort"
+	"strings"
+)
+
+// CallGraphNode is a function in a CallGraph.
+type CallGraphNode struct {
+	Name string
+
+	// Size is the size of the function in bytes. It is zero for
+	// functions that radare2 has not analyzed, such as imports.
+	Size uint64
+
+	// Complexity is the cyclomatic complexity of the function. It is
+	// only set if the graph has been annotated with Annotate.
+	Complexity int
+
+	// Imported is true if the function is an import.
+	Imported bool
+
+	// Callees are the names of the functions this function calls.
+	Callees []string
+}
+
+// CallGraph is a graph of calls between functions.
+type CallGraph struct {
+	nodes   map[string]*CallGraphNode
+	callers map[string][]string
+}
+
+// Nodes returns the names of the functions in the graph in
+// lexicographic order.
+func (o *CallGraph) Nodes() []string {
+	names := make([]string, 0, len(o.nodes))
+	for name := range o.nodes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Node returns the node for the named function.
+func (o *CallGraph) Node(name string) (CallGraphNode, bool) {
+	node, exists := o.nodes[name]
+	if !exists {
+		return CallGraphNode{}, false
+	}
+
+	return *node, true
+}
+
+// Callees returns the names of the functions called by the named function.
+func (o *CallGraph) Callees(name string) []string {
+	node, exists := o.nodes[name]
+	if !exists {
+		return nil
+	}
+
+	return node.Callees
+}
+
+// Callers returns the names of the functions that call the named function.
+func (o *CallGraph) Callers(name string) []string {
+	return o.callers[name]
+}
+
+// ReachableFrom returns the names of the functions that can be reached
+// by following calls from the named function, in lexicographic order.
+// The named function is only included if it can reach itself.
+func (o *CallGraph) ReachableFrom(name string) []string {
+	visited := make(map[string]bool)
+	stack := append([]string(nil), o.Callees(name)...)
+
+	for len(stack) > 0 {
+		current := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		if visited[current] {
+			continue
+		}
+		visited[current] = true
+
+		stack = append(stack, o.Callees(current)...)
+	}
+
+	names := make([]string, 0, len(visited))
+	for name := range visited {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// IsReachable returns true if the function named to can be reached by
+// following calls from the function named from.
+func (o *CallGraph) IsReachable(from string, to string) bool {
+	return containsString(o.ReachableFrom(from), to)
+}
+
+// HasCycles returns true if the graph contains recursive calls.
+func (o *CallGraph) HasCycles() bool {
+	return len(o.Cycles()) > 0
+}
+
+// Cycles returns the groups of functions that call each other
+// recursively. Each group is a strongly connected component of the
+// graph that contains more than one function, or a single function
+// that calls itself. The names in each group are sorted, and the
+// groups are sorted by their first name.
+func (o *CallGraph) Cycles() [][]string {
+	// Tarjan's strongly connected components algorithm.
+	index := 0
+	indexes := make(map[string]int)
+	lowLinks := make(map[string]int)
+	onStack := make(map[string]bool)
+	var stack []string
+	var cycles [][]string
+
+	var connect func(name string)
+	connect = func(name string) {
+		indexes[name] = index
+		lowLinks[name] = index
+		index++
+		stack = append(stack, name)
+		onStack[name] = true
+
+		for _, callee := range o.Callees(name) {
+			if _, visited := indexes[callee]; !visited {
+				connect(callee)
+				if lowLinks[callee] < lowLinks[name] {
+					lowLinks[name] = lowLinks[callee]
+				}
+			} else if onStack[callee] && indexes[callee] < lowLinks[name] {
+				lowLinks[name] = indexes[callee]
+			}
+		}
+
+		if lowLinks[name] != indexes[name] {
+			return
+		}
+
+		var component []string
+		for {
+			last := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[last] = false
+			component = append(component, last)
+			if last == name {
+				break
+			}
+		}
+
+		if len(component) > 1 || o.callsItself(name) {
+			sort.Strings(component)
+			cycles = append(cycles, component)
+		}
+	}
+
+	for _, name := range o.Nodes() {
+		if _, visited := indexes[name]; !visited {
+			connect(name)
+		}
+	}
+
+	sort.Slice(cycles, func(i int, j int) bool {
+		return cycles[i][0] < cycles[j][0]
+	})
+
+	return cycles
+}
+
+func (o *CallGraph) callsItself(name string) bool {
+	return containsString(o.Callees(name), name)
+}
+
+func (o *CallGraph) addNode(name string) *CallGraphNode {
+	node, exists := o.nodes[name]
+	if !exists {
+		node = &CallGraphNode{
+			Name:     name,
+			Imported: strings.HasPrefix(name, "sym.imp."),
+		}
+		o.nodes[name] = node
+	}
+
+	return node
+}
+
+func (o *CallGraph) addEdge(from string, to string) {
+	o.addNode(to)
+
+	node := o.addNode(from)
+	if containsString(node.Callees, to) {
+		return
+	}
+
+	node.Callees = append(node.Callees, to)
+	o.callers[to] = append(o.callers[to], from)
+}
+
+func newCallGraph() *CallGraph {
+	return &CallGraph{
+		nodes:   make(map[string]*CallGraphNode),
+		callers: make(map[string][]string),
+	}
+}
+
+type rawCallGraphNode struct {
+	Name    string   `json:"name"`
+	Size    uint64   `json:"size"`
+	Imports []string `json:"imports"`
+}
+
+// GlobalCallGraph returns the call graph of all functions that radare2
+// has analyzed ('agCj'). Functions must be analyzed beforehand, for
+// example by executing 'aaa'.
+func GlobalCallGraph(api Api) (*CallGraph, error) {
+	var raws []rawCallGraphNode
+	err := api.ExecuteToJson("agCj", &raws)
+	if err != nil {
+		return nil, err
+	}
+
+	graph := newCallGraph()
+
+	for _, raw := range raws {
+		graph.addNode(raw.Name).Size = raw.Size
+
+		for _, callee := range raw.Imports {
+			graph.addEdge(raw.Name, callee)
+		}
+	}
+
+	return graph, nil
+}
+
+func containsString(strs []string, str string) bool {
+	for i := range strs {
+		if strs[i] == str {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,343 @@
+package radareutil
+
+import (
+	"bufio"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const (
+	// CollapsedImportsNode is the name of the node that replaces
+	// imported functions when CallGraphExportOptions.CollapseImports
+	// is set.
+	CollapsedImportsNode = "imports"
+)
+
+type CallGraphFormat string
+
+func (o CallGraphFormat) String() string {
+	return string(o)
+}
+
+const (
+	CallGraphDot     CallGraphFormat = "dot"
+	CallGraphGraphML CallGraphFormat = "graphml"
+	CallGraphJson    CallGraphFormat = "json"
+)
+
+// CallGraphExportOptions configures ExportCallGraph.
+type CallGraphExportOptions struct {
+	// Root, if non-empty, limits the graph to the functions that can
+	// be reached from the named function.
+	Root string
+
+	// MaxDepth limits the graph to functions that are at most this
+	// many calls away from Root. Zero means there is no limit.
+	MaxDepth int
+
+	// CollapseImports replaces all imported functions with a single
+	// node named CollapsedImportsNode.
+	CollapseImports bool
+
+	// Annotate sets the size and cyclomatic complexity of each
+	// function using the output of 'aflj'.
+	Annotate bool
+}
+
+// ExportCallGraph queries the global call graph and writes it to w
+// in the specified format. A nil options is equivalent to an empty
+// CallGraphExportOptions.
+func ExportCallGraph(api Api, w io.Writer, format CallGraphFormat, options *CallGraphExportOptions) error {
+	if options == nil {
+		options = &CallGraphExportOptions{}
+	}
+
+	graph, err := GlobalCallGraph(api)
+	if err != nil {
+		return err
+	}
+
+	if options.Annotate {
+		err := graph.Annotate(api)
+		if err != nil {
+			return err
+		}
+	}
+
+	if options.Root != "" {
+		graph, err = graph.Subgraph(options.Root, options.MaxDepth)
+		if err != nil {
+			return err
+		}
+	}
+
+	if options.CollapseImports {
+		graph = graph.CollapseImports()
+	}
+
+	switch format {
+	case CallGraphDot:
+		return graph.WriteDot(w)
+	case CallGraphGraphML:
+		return graph.WriteGraphML(w)
+	case CallGraphJson:
+		return graph.WriteJson(w)
+	default:
+		return fmt.Errorf("unknown call graph format '%s'", format.String())
+	}
+}
+
+// Annotate sets the size and cyclomatic complexity of each function in
+// the graph using the output of 'aflj'.
+func (o *CallGraph) Annotate(api Api) error {
+	functions, err := Functions(api)
+	if err != nil {
+		return err
+	}
+
+	for _, function := range functions {
+		node, exists := o.nodes[function.Name]
+		if !exists {
+			continue
+		}
+
+		node.Size = function.Size
+		node.Complexity = function.Complexity
+	}
+
+	return nil
+}
+
+// Subgraph returns a new graph containing the named function and the
+// functions that can be reached from it in at most maxDepth calls,
+// along with all calls between them. A maxDepth of zero means there
+// is no limit.
+func (o *CallGraph) Subgraph(root string, maxDepth int) (*CallGraph, error) {
+	rootNode, exists := o.nodes[root]
+	if !exists {
+		return nil, fmt.Errorf("function '%s' is not in the call graph", root)
+	}
+
+	graph := newCallGraph()
+	*graph.addNode(root) = CallGraphNode{
+		Name:       rootNode.Name,
+		Size:       rootNode.Size,
+		Complexity: rootNode.Complexity,
+		Imported:   rootNode.Imported,
+	}
+
+	depths := map[string]int{
+		root: 0,
+	}
+	included := []string{root}
+	queue := []string{root}
+
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+
+		if maxDepth > 0 && depths[current] >= maxDepth {
+			continue
+		}
+
+		for _, callee := range o.Callees(current) {
+			if _, visited := depths[callee]; visited {
+				continue
+			}
+
+			depths[callee] = depths[current] + 1
+			included = append(included, callee)
+			queue = append(queue, callee)
+
+			node := o.nodes[callee]
+			*graph.addNode(callee) = CallGraphNode{
+				Name:       node.Name,
+				Size:       node.Size,
+				Complexity: node.Complexity,
+				Imported:   node.Imported,
+			}
+		}
+	}
+
+	// Calls made by functions at maxDepth are kept if the callee
+	// is also in the graph.
+	for _, name := range included {
+		for _, callee := range o.Callees(name) {
+			if _, isIncluded := depths[callee]; isIncluded {
+				graph.addEdge(name, callee)
+			}
+		}
+	}
+
+	return graph, nil
+}
+
+// CollapseImports returns a new graph in which all imported functions
+// are replaced by a single node named CollapsedImportsNode.
+func (o *CallGraph) CollapseImports() *CallGraph {
+	graph := newCallGraph()
+
+	rename := func(name string) string {
+		if o.nodes[name].Imported {
+			return CollapsedImportsNode
+		}
+		return name
+	}
+
+	for _, name := range o.Nodes() {
+		node := o.nodes[name]
+		if node.Imported {
+			graph.addNode(CollapsedImportsNode).Imported = true
+			continue
+		}
+
+		*graph.addNode(name) = CallGraphNode{
+			Name:       node.Name,
+			Size:       node.Size,
+			Complexity: node.Complexity,
+		}
+	}
+
+	for _, name := range o.Nodes() {
+		for _, callee := range o.Callees(name) {
+			graph.addEdge(rename(name), rename(callee))
+		}
+	}
+
+	return graph
+}
+
+// WriteDot writes the graph to w in Graphviz DOT format.
+func (o *CallGraph) WriteDot(w io.Writer) error {
+	buff := bufio.NewWriter(w)
+
+	buff.WriteString("digraph callgraph {\n")
+
+	for _, name := range o.Nodes() {
+		node := o.nodes[name]
+		label := node.Name
+		if node.Size > 0 || node.Complexity > 0 {
+			label = fmt.Sprintf("%s\nsize: %d\ncomplexity: %d",
+				node.Name, node.Size, node.Complexity)
+		}
+
+		shape := "box"
+		if node.Imported {
+			shape = "ellipse"
+		}
+
+		buff.WriteString(fmt.Sprintf("\t%s [label=%s, shape=%s];\n",
+			dotQuote(name), dotQuote(label), shape))
+	}
+
+	for _, name := range o.Nodes() {
+		for _, callee := range o.Callees(name) {
+			buff.WriteString(fmt.Sprintf("\t%s -> %s;\n", dotQuote(name), dotQuote(callee)))
+		}
+	}
+
+	buff.WriteString("}\n")
+
+	return buff.Flush()
+}
+
+// dotQuote returns str as a quoted DOT ID. Backslashes and quotes are
+// escaped, and new lines are replaced with the DOT escape sequence '\n'.
+func dotQuote(str string) string {
+	str = strings.Replace(str, `\`, `\\`, -1)
+	str = strings.Replace(str, `"`, `\"`, -1)
+	str = strings.Replace(str, "\n", `\n`, -1)
+
+	return `"` + str + `"`
+}
+
+// WriteGraphML writes the graph to w in GraphML format, which can be
+// imported by tools such as Gephi and yEd.
+func (o *CallGraph) WriteGraphML(w io.Writer) error {
+	buff := bufio.NewWriter(w)
+
+	buff.WriteString(xml.Header)
+	buff.WriteString(`<graphml xmlns="http://graphml.graphdrawing.org/xmlns">` + "\n")
+	buff.WriteString(`  <key id="label" for="node" attr.name="label" attr.type="string"/>` + "\n")
+	buff.WriteString(`  <key id="size" for="node" attr.name="size" attr.type="long"/>` + "\n")
+	buff.WriteString(`  <key id="complexity" for="node" attr.name="complexity" attr.type="int"/>` + "\n")
+	buff.WriteString(`  <key id="imported" for="node" attr.name="imported" attr.type="boolean"/>` + "\n")
+	buff.WriteString(`  <graph id="callgraph" edgedefault="directed">` + "\n")
+
+	for _, name := range o.Nodes() {
+		node := o.nodes[name]
+		buff.WriteString(fmt.Sprintf("    <node id=\"%s\">\n", xmlEscape(name)))
+		buff.WriteString(fmt.Sprintf("      <data key=\"label\">%s</data>\n", xmlEscape(node.Name)))
+		buff.WriteString(fmt.Sprintf("      <data key=\"size\">%d</data>\n", node.Size))
+		buff.WriteString(fmt.Sprintf("      <data key=\"complexity\">%d</data>\n", node.Complexity))
+		buff.WriteString(fmt.Sprintf("      <data key=\"imported\">%s</data>\n", strconv.FormatBool(node.Imported)))
+		buff.WriteString("    </node>\n")
+	}
+
+	for _, name := range o.Nodes() {
+		for _, callee := range o.Callees(name) {
+			buff.WriteString(fmt.Sprintf("    <edge source=\"%s\" target=\"%s\"/>\n",
+				xmlEscape(name), xmlEscape(callee)))
+		}
+	}
+
+	buff.WriteString("  </graph>\n")
+	buff.WriteString("</graphml>\n")
+
+	return buff.Flush()
+}
+
+func xmlEscape(str string) string {
+	var buff strings.Builder
+	xml.EscapeText(&buff, []byte(str))
+	return buff.String()
+}
+
+type callGraphJson struct {
+	Nodes []callGraphJsonNode `json:"nodes"`
+	Edges []callGraphJsonEdge `json:"edges"`
+}
+
+type callGraphJsonNode struct {
+	Name       string `json:"name"`
+	Size       uint64 `json:"size"`
+	Complexity int    `json:"complexity"`
+	Imported   bool   `json:"imported"`
+}
+
+type callGraphJsonEdge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// WriteJson writes the graph to w as a JSON object containing
+// a list of nodes and a list of edges.
+func (o *CallGraph) WriteJson(w io.Writer) error {
+	out := callGraphJson{
+		Nodes: []callGraphJsonNode{},
+		Edges: []callGraphJsonEdge{},
+	}
+
+	for _, name := range o.Nodes() {
+		node := o.nodes[name]
+		out.Nodes = append(out.Nodes, callGraphJsonNode{
+			Name:       node.Name,
+			Size:       node.Size,
+			Complexity: node.Complexity,
+			Imported:   node.Imported,
+		})
+
+		for _, callee := range node.Callees {
+			out.Edges = append(out.Edges, callGraphJsonEdge{
+				From: name,
+				To:   callee,
+			})
+		}
+	}
+
+	return json.NewEncoder(w).Encode(out)
+}
@@ -0,0 +1,57 @@
+package radareutil
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDotQuote(t *testing.T) {
+	tests := []struct {
+		str      string
+		expected string
+	}{
+		{str: "main", expected: `"main"`},
+		{str: `say "hi"`, expected: `"say \"hi\""`},
+		{str: `a\`, expected: `"a\\"`},
+		{str: `a\"b`, expected: `"a\\\"b"`},
+		{str: "main\nsize: 4", expected: `"main\nsize: 4"`},
+	}
+
+	for _, test := range tests {
+		result := dotQuote(test.str)
+		if result != test.expected {
+			t.Errorf("dotQuote(%q) = %s - expected %s", test.str, result, test.expected)
+		}
+	}
+}
+
+func TestSubgraph(t *testing.T) {
+	graph := newCallGraph()
+	graph.addEdge("main", "a")
+	graph.addEdge("main", "b")
+	graph.addEdge("a", "b")
+	graph.addEdge("a", "c")
+	graph.addEdge("b", "main")
+
+	subgraph, err := graph.Subgraph("main", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string][]string{
+		"main": {"a", "b"},
+		"a":    {"b"},
+		"b":    {"main"},
+	}
+
+	nodes := subgraph.Nodes()
+	if len(nodes) != len(expected) {
+		t.Fatalf("subgraph nodes are %v - expected %d nodes", nodes, len(expected))
+	}
+
+	for name, callees := range expected {
+		if !reflect.DeepEqual(subgraph.Callees(name), callees) {
+			t.Errorf("'%s' calls %v - expected %v", name, subgraph.Callees(name), callees)
+		}
+	}
+}
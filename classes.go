@@ -0,0 +1,210 @@
+package radareutil
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// ClassMethod is a method of a class.
+type ClassMethod struct {
+	Name  string   `json:"name"`
+	Addr  uint64   `json:"addr"`
+	Flags []string `json:"flags"`
+}
+
+// ClassField is a field of a class.
+type ClassField struct {
+	Name  string   `json:"name"`
+	Addr  uint64   `json:"addr"`
+	Flags []string `json:"flags"`
+}
+
+// Class is a class as reported by 'icj'. radare2 reports classes for
+// C++, Objective-C, Swift, Java, and Dex binaries.
+type Class struct {
+	Name  string
+	Addr  uint64
+	Lang  string
+	Index int
+
+	// Superclasses are the names of the classes the class inherits from.
+	Superclasses []string
+
+	Methods []ClassMethod
+	Fields  []ClassField
+}
+
+type rawClass struct {
+	ClassName string          `json:"classname"`
+	Addr      uint64          `json:"addr"`
+	Lang      string          `json:"lang"`
+	Index     int             `json:"index"`
+	Super     json.RawMessage `json:"super"`
+	Methods   []ClassMethod   `json:"methods"`
+	Fields    []ClassField    `json:"fields"`
+}
+
+// Classes returns the classes defined by the binary ('icj').
+func Classes(api Api) ([]Class, error) {
+	var raws []rawClass
+	err := api.ExecuteToJson("icj", &raws)
+	if err != nil {
+		return nil, err
+	}
+
+	classes := make([]Class, len(raws))
+	for i, raw := range raws {
+		// Depending on the version of radare2, 'super'
+		// is either a string or a list of strings.
+		var superclasses []string
+		if len(raw.Super) > 0 && json.Unmarshal(raw.Super, &superclasses) != nil {
+			var superclass string
+			err := json.Unmarshal(raw.Super, &superclass)
+			if err != nil {
+				return nil, fmt.Errorf("failed to decode superclass of '%s' - %s", raw.ClassName, err.Error())
+			}
+
+			if superclass != "" {
+				superclasses = []string{superclass}
+			}
+		}
+
+		classes[i] = Class{
+			Name:         raw.ClassName,
+			Addr:         raw.Addr,
+			Lang:         raw.Lang,
+			Index:        raw.Index,
+			Superclasses: superclasses,
+			Methods:      raw.Methods,
+			Fields:       raw.Fields,
+		}
+	}
+
+	return classes, nil
+}
+
+// FindClass returns the named class.
+func FindClass(classes []Class, name string) (Class, bool) {
+	for _, class := range classes {
+		if class.Name == name {
+			return class, true
+		}
+	}
+
+	return Class{}, false
+}
+
+// ClassHierarchyText renders the inheritance hierarchy of classes as
+// a tree. Superclasses that are not defined by the binary, such as
+// NSObject, are included as roots. A class with multiple superclasses
+// appears under each of them. Classes that only inherit from each
+// other cyclically are included as roots after the other classes.
+// For example:
+//
+//	NSObject
+//	├── Animal
+//	│   ├── Cat
+//	│   └── Dog
+//	└── Plant
+func ClassHierarchyText(classes []Class) string {
+	buff := bytes.NewBuffer(nil)
+	writeClassHierarchy(buff, classes)
+
+	return buff.String()
+}
+
+// WriteClassHierarchy writes the tree rendered by ClassHierarchyText to w.
+func WriteClassHierarchy(w io.Writer, classes []Class) error {
+	buff := bytes.NewBuffer(nil)
+	writeClassHierarchy(buff, classes)
+
+	_, err := w.Write(buff.Bytes())
+	return err
+}
+
+func writeClassHierarchy(buff *bytes.Buffer, classes []Class) {
+	defined := make(map[string]bool)
+	subclasses := make(map[string][]string)
+	for _, class := range classes {
+		defined[class.Name] = true
+		for _, superclass := range class.Superclasses {
+			if !containsString(subclasses[superclass], class.Name) {
+				subclasses[superclass] = append(subclasses[superclass], class.Name)
+			}
+		}
+	}
+
+	var roots []string
+	for _, class := range classes {
+		if len(class.Superclasses) == 0 && !containsString(roots, class.Name) {
+			roots = append(roots, class.Name)
+		}
+
+		for _, superclass := range class.Superclasses {
+			if !defined[superclass] && !containsString(roots, superclass) {
+				roots = append(roots, superclass)
+			}
+		}
+	}
+
+	sort.Strings(roots)
+	for name := range subclasses {
+		sort.Strings(subclasses[name])
+	}
+
+	visited := make(map[string]bool)
+	writeRoot := func(root string) {
+		visited[root] = true
+		buff.WriteString(root)
+		buff.WriteString("\n")
+		writeSubclasses(buff, subclasses, root, "", []string{root}, visited)
+	}
+
+	for _, root := range roots {
+		writeRoot(root)
+	}
+
+	// Classes in an inheritance cycle cannot be reached from a root.
+	var unvisited []string
+	for _, class := range classes {
+		if !visited[class.Name] && !containsString(unvisited, class.Name) {
+			unvisited = append(unvisited, class.Name)
+		}
+	}
+	sort.Strings(unvisited)
+
+	for _, name := range unvisited {
+		if !visited[name] {
+			writeRoot(name)
+		}
+	}
+}
+
+// writeSubclasses writes the subclasses of name. path contains the
+// classes leading to name, which prevents cyclic inheritance from
+// recursing forever. Each class that is written is added to visited.
+func writeSubclasses(buff *bytes.Buffer, subclasses map[string][]string, name string, indent string, path []string, visited map[string]bool) {
+	children := subclasses[name]
+	for i, child := range children {
+		branch, childIndent := "├── ", "│   "
+		if i == len(children)-1 {
+			branch, childIndent = "└── ", "    "
+		}
+
+		buff.WriteString(indent)
+		buff.WriteString(branch)
+		buff.WriteString(child)
+		visited[child] = true
+
+		if containsString(path, child) {
+			buff.WriteString(" (cycle)\n")
+			continue
+		}
+		buff.WriteString("\n")
+
+		writeSubclasses(buff, subclasses, child, indent+childIndent, append(path, child), visited)
+	}
+}
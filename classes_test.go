@@ -0,0 +1,24 @@
+package radareutil
+
+import (
+	"testing"
+)
+
+func TestClassHierarchyTextCycle(t *testing.T) {
+	classes := []Class{
+		{Name: "Animal", Superclasses: []string{"NSObject"}},
+		{Name: "B", Superclasses: []string{"A"}},
+		{Name: "A", Superclasses: []string{"B"}},
+	}
+
+	expected := "NSObject\n" +
+		"└── Animal\n" +
+		"A\n" +
+		"└── B\n" +
+		"    └── A (cycle)\n"
+
+	text := ClassHierarchyText(classes)
+	if text != expected {
+		t.Errorf("hierarchy is\n%s\nexpected\n%s", text, expected)
+	}
+}
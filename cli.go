@@ -1,14 +1,51 @@
 package radareutil
 
 import (
+	"bufio"
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
 )
 
+const (
+	// commandTimeoutRecoveryWait is the amount of time to wait for
+	// radare2 to finish a command after it has been interrupted.
+	commandTimeoutRecoveryWait = 5 * time.Second
+
+	// streamDrainWait is the amount of time to wait for radare2 to
+	// finish writing the output of a closed stream before the command
+	// is interrupted.
+	streamDrainWait = 1 * time.Second
+
+	// abandonReadWait is the amount of time to wait for the output
+	// of a command to be read after radare2 has been killed.
+	abandonReadWait = 1 * time.Second
+)
+
+// CommandTimeoutError is returned when a command does not finish within
+// Radare2Config.CommandTimeout. If radare2 recovered from the interrupt,
+// the session can continue to be used. Otherwise, radare2 is killed and
+// its state is set to Dead. In either case, PartialOutput contains the
+// output that was read before radare2 finished or exited.
+type CommandTimeoutError struct {
+	Command       string
+	Timeout       time.Duration
+	PartialOutput []byte
+}
+
+func (o *CommandTimeoutError) Error() string {
+	return fmt.Sprintf("command '%s' timed out after %s", o.Command, o.Timeout.String())
+}
+
 type cliApi struct {
 	config *Radare2Config
 	r2     *r2Proc
+	mutex  *sync.Mutex
 }
 
 func (o *cliApi) Start() error {
@@ -42,6 +79,10 @@ func (o *cliApi) OnStopped() chan StoppedInfo {
 	return o.r2.onStopped()
 }
 
+func (o *cliApi) debuggeeExitStatus(pid int) (int, bool) {
+	return o.r2.debuggeeExitStatus(pid)
+}
+
 func (o *cliApi) ExecuteToJson(c string, p interface{}) error {
 	output, err := o.ExecuteToBytes(c)
 	if err != nil {
@@ -66,26 +107,277 @@ func (o *cliApi) Execute(cmd string) (string, error) {
 }
 
 func (o *cliApi) ExecuteToBytes(cmd string) ([]byte, error) {
-	current := o.r2.status().State
-	if current != Running {
-		return nil, fmt.Errorf("cannot execute command - state is %s", current)
-	}
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
 
-	_, err := o.r2.stdin.Write([]byte(cmd + "\n"))
+	err := o.writeCommand(cmd)
 	if err != nil {
 		return nil, err
 	}
 
+	if o.config.CommandTimeout > 0 {
+		raw, err := o.readOutputWithTimeout(cmd)
+		if err != nil {
+			if timeoutErr, isTimeout := err.(*CommandTimeoutError); isTimeout {
+				timeoutErr.PartialOutput = o.trim(timeoutErr.PartialOutput)
+				return timeoutErr.PartialOutput, timeoutErr
+			}
+			return nil, err
+		}
+
+		return o.trim(raw), nil
+	}
+
 	raw, err := o.r2.stdout.ReadBytes(0x00)
 	if err != nil {
 		return nil, err
 	}
 
+	return o.trim(raw), nil
+}
+
+// ExecuteStream executes a command and returns its output as it is
+// produced by radare2. The output is not trimmed and is not subject to
+// Radare2Config.CommandTimeout. Other commands cannot be executed until
+// the returned io.ReadCloser is closed. Closing it before all of the
+// output has been read discards the remaining output, interrupting
+// the command if it does not finish shortly.
+func (o *cliApi) ExecuteStream(cmd string) (io.ReadCloser, error) {
+	o.mutex.Lock()
+
+	err := o.writeCommand(cmd)
+	if err != nil {
+		o.mutex.Unlock()
+		return nil, err
+	}
+
+	return &cliOutputStream{
+		api: o,
+	}, nil
+}
+
+func (o *cliApi) writeCommand(cmd string) error {
+	current := o.r2.status().State
+	if current != Running {
+		return fmt.Errorf("cannot execute command - state is %s", current)
+	}
+
+	_, err := o.r2.stdin.Write([]byte(cmd + "\n"))
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (o *cliApi) trim(raw []byte) []byte {
 	if o.config.DoNotTrimOutput {
-		return raw, nil
+		return raw
+	}
+
+	return bytes.TrimRight(raw, "\n\x00")
+}
+
+type readResult struct {
+	raw []byte
+	err error
+}
+
+// readOutputWithTimeout reads the output of a command. If the command
+// does not finish before the timeout, it is interrupted and any output
+// produced up to that point is included in the returned error.
+func (o *cliApi) readOutputWithTimeout(cmd string) ([]byte, error) {
+	results := make(chan readResult, 1)
+
+	go func() {
+		raw, err := o.r2.stdout.ReadBytes(0x00)
+		results <- readResult{
+			raw: raw,
+			err: err,
+		}
+	}()
+
+	timeout := time.NewTimer(o.config.CommandTimeout)
+	defer timeout.Stop()
+
+	select {
+	case result := <-results:
+		return result.raw, result.err
+	case <-timeout.C:
+	}
+
+	timeoutErr := &CommandTimeoutError{
+		Command: cmd,
+		Timeout: o.config.CommandTimeout,
+	}
+
+	result, _ := o.interruptAndWait(results)
+	timeoutErr.PartialOutput = result.raw
+
+	return nil, timeoutErr
+}
+
+// interruptAndWait interrupts the current command and waits for the
+// result of reading its remaining output. If radare2 does not recover
+// from the interrupt, it is killed and marked as dead.
+func (o *cliApi) interruptAndWait(results chan readResult) (readResult, error) {
+	err := o.r2.interrupt()
+	if err != nil {
+		err = fmt.Errorf("failed to interrupt command - %s", err.Error())
+		return o.abandonAndWait(results, err), err
+	}
+
+	recovery := time.NewTimer(commandTimeoutRecoveryWait)
+	defer recovery.Stop()
+
+	select {
+	case result := <-results:
+		if result.err != nil {
+			err = fmt.Errorf("failed to read output of interrupted command - %s", result.err.Error())
+			o.r2.abandon(err)
+			return result, err
+		}
+		return result, nil
+	case <-recovery.C:
+		err = errors.New("radare2 did not recover from interrupting a command")
+		return o.abandonAndWait(results, err), err
+	}
+}
+
+// abandonAndWait kills radare2 and marks it as dead. It then waits for
+// the result of reading the command's output, which ends once radare2's
+// stdout is closed, so that the output read so far is not lost.
+func (o *cliApi) abandonAndWait(results chan readResult, err error) readResult {
+	o.r2.abandon(err)
+
+	wait := time.NewTimer(abandonReadWait)
+	defer wait.Stop()
+
+	select {
+	case result := <-results:
+		return result
+	case <-wait.C:
+		return readResult{}
 	}
+}
+
+// cliOutputStream reads the output of a single command up to,
+// but not including, the NUL terminator.
+type cliOutputStream struct {
+	api    *cliApi
+	done   bool
+	closed bool
+}
 
-	return bytes.TrimRight(raw, "\n\x00"), nil
+func (o *cliOutputStream) Read(p []byte) (int, error) {
+	if o.closed {
+		return 0, errors.New("stream is closed")
+	}
+
+	if o.done {
+		return 0, io.EOF
+	}
+
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	stdout := o.api.r2.stdout
+
+	// Block until at least one byte is available.
+	_, err := stdout.Peek(1)
+	if err != nil {
+		o.done = true
+		if isClosedOutputError(err) {
+			// radare2 exited before terminating the output.
+			return 0, io.ErrUnexpectedEOF
+		}
+		return 0, err
+	}
+
+	n := stdout.Buffered()
+	if n > len(p) {
+		n = len(p)
+	}
+
+	buff, err := stdout.Peek(n)
+	if err != nil {
+		return 0, err
+	}
+
+	if end := bytes.IndexByte(buff, 0x00); end >= 0 {
+		copy(p, buff[:end])
+		stdout.Discard(end + 1)
+		o.done = true
+		if end == 0 {
+			return 0, io.EOF
+		}
+		return end, nil
+	}
+
+	copy(p, buff)
+	stdout.Discard(n)
+
+	return n, nil
+}
+
+func (o *cliOutputStream) Close() error {
+	if o.closed {
+		return nil
+	}
+
+	o.closed = true
+	defer o.api.mutex.Unlock()
+
+	if o.done {
+		return nil
+	}
+
+	results := make(chan readResult, 1)
+
+	go func() {
+		err := discardUntilNul(o.api.r2.stdout)
+		results <- readResult{
+			err: err,
+		}
+	}()
+
+	drain := time.NewTimer(streamDrainWait)
+	defer drain.Stop()
+
+	select {
+	case result := <-results:
+		return result.err
+	case <-drain.C:
+	}
+
+	_, err := o.api.interruptAndWait(results)
+
+	return err
+}
+
+// isClosedOutputError returns true if err indicates that radare2's stdout
+// was closed. Depending on whether the process has been waited for, this
+// is either io.EOF or an error about the pipe already being closed.
+func isClosedOutputError(err error) bool {
+	if err == io.EOF {
+		return true
+	}
+
+	pathErr, isPathErr := err.(*os.PathError)
+	return isPathErr && pathErr.Err == os.ErrClosed
+}
+
+// discardUntilNul reads and discards data from reader up to and
+// including the next NUL byte.
+func discardUntilNul(reader *bufio.Reader) error {
+	for {
+		_, err := reader.ReadSlice(0x00)
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+		return err
+	}
 }
 
 func NewCliApi(config *Radare2Config) (Api, error) {
@@ -97,5 +389,6 @@ func NewCliApi(config *Radare2Config) (Api, error) {
 	return &cliApi{
 		config: config,
 		r2:     r2,
+		mutex:  &sync.Mutex{},
 	}, nil
 }
@@ -0,0 +1,179 @@
+package radareutil
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+// fakeRadare2Timeout is a fake radare2 with commands that do not finish.
+// 'slow' finishes when it is interrupted, 'hang' ignores the interrupt,
+// and 'exit' exits without terminating its output.
+const fakeRadare2Timeout = `#!/bin/sh
+printf '\000'
+while IFS= read -r line; do
+	case "$line" in
+	slow)
+		printf 'partial'
+		trap ':' INT
+		sleep 60 &
+		pid=$!
+		wait $pid
+		kill $pid
+		trap - INT
+		printf ' interrupted\000'
+		;;
+	hang)
+		printf 'partial'
+		trap '' INT
+		exec sleep 60
+		;;
+	exit)
+		printf 'partial'
+		exit 1
+		;;
+	*)
+		printf '%s\n\000' "$line"
+		;;
+	esac
+done
+`
+
+func startFakeCliApi(t *testing.T, script string, timeout time.Duration) (Api, func()) {
+	dir, err := ioutil.TempDir("", "radareutil-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	api, err := NewCliApi(&Radare2Config{
+		ExecutablePath: writeFakeRadare2(t, dir, script),
+		CommandTimeout: timeout,
+	})
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+
+	err = api.Start()
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+
+	return api, func() {
+		api.Kill()
+		os.RemoveAll(dir)
+	}
+}
+
+func TestCommandTimeoutRecovered(t *testing.T) {
+	api, cleanup := startFakeCliApi(t, fakeRadare2Timeout, 200*time.Millisecond)
+	defer cleanup()
+
+	output, err := api.Execute("slow")
+	timeoutErr, isTimeout := err.(*CommandTimeoutError)
+	if !isTimeout {
+		t.Fatalf("expected a *CommandTimeoutError - got %v", err)
+	}
+
+	if string(timeoutErr.PartialOutput) != "partial interrupted" || output != "partial interrupted" {
+		t.Errorf("partial output is %q - expected %q", timeoutErr.PartialOutput, "partial interrupted")
+	}
+
+	if state := api.Status().State; state != Running {
+		t.Fatalf("state is %s after recovering from a timeout", state)
+	}
+
+	output, err = api.Execute("echo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if output != "echo" {
+		t.Errorf("output is %q - expected %q", output, "echo")
+	}
+}
+
+func TestCommandTimeoutAbandoned(t *testing.T) {
+	api, cleanup := startFakeCliApi(t, fakeRadare2Timeout, 200*time.Millisecond)
+	defer cleanup()
+
+	_, err := api.Execute("hang")
+	timeoutErr, isTimeout := err.(*CommandTimeoutError)
+	if !isTimeout {
+		t.Fatalf("expected a *CommandTimeoutError - got %v", err)
+	}
+
+	if string(timeoutErr.PartialOutput) != "partial" {
+		t.Errorf("partial output is %q - expected %q", timeoutErr.PartialOutput, "partial")
+	}
+
+	if state := api.Status().State; state != Dead {
+		t.Errorf("state is %s after radare2 did not recover from a timeout", state)
+	}
+}
+
+func TestExecuteStream(t *testing.T) {
+	api, cleanup := startFakeCliApi(t, fakeRadare2Timeout, 0)
+	defer cleanup()
+
+	stream, err := ExecuteStream(api, "echo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	output, err := ioutil.ReadAll(stream)
+	stream.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(output) != "echo\n" {
+		t.Errorf("output is %q - expected %q", output, "echo\n")
+	}
+}
+
+func TestExecuteStreamUnexpectedExit(t *testing.T) {
+	api, cleanup := startFakeCliApi(t, fakeRadare2Timeout, 0)
+	defer cleanup()
+
+	stream, err := ExecuteStream(api, "exit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close()
+
+	_, err = ioutil.ReadAll(stream)
+	if err != io.ErrUnexpectedEOF {
+		t.Fatalf("expected io.ErrUnexpectedEOF - got %v", err)
+	}
+}
+
+// bytesApi is an Api that does not implement StreamApi.
+type bytesApi struct {
+	Api
+	output []byte
+}
+
+func (o *bytesApi) ExecuteToBytes(command string) ([]byte, error) {
+	return o.output, nil
+}
+
+func TestExecuteStreamFallback(t *testing.T) {
+	stream, err := ExecuteStream(&bytesApi{output: []byte("output")}, "pd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stream.Close()
+
+	output, err := ioutil.ReadAll(stream)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(output) != "output" {
+		t.Errorf("output is %q - expected %q", output, "output")
+	}
+}
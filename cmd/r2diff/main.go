@@ -0,0 +1,87 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/stephen-fox/radareutil"
+)
+
+func main() {
+	exePath := flag.String("r", "radare2", "The radare2 executable path")
+	analysis := flag.String("a", "aaa", "The radare2 command used to analyze the binaries")
+	jsonOutput := flag.Bool("j", false, "Write the diff as JSON")
+	includeUnchanged := flag.Bool("u", false, "Include unchanged functions")
+	help := flag.Bool("h", false, "Displays this help page")
+
+	flag.Parse()
+
+	if *help || flag.NArg() != 2 {
+		os.Stderr.WriteString(`r2diff [options] <binary-a> <binary-b>
+
+Compares the functions of two binaries using radare2. Added, removed, and
+changed functions are reported. The basic blocks of changed functions are
+displayed side by side, with binary A on the left and binary B on the right.
+
+options:
+`)
+		flag.PrintDefaults()
+		os.Exit(1)
+	}
+
+	a, err := startApi(*exePath, flag.Arg(0), *analysis)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	b, err := startApi(*exePath, flag.Arg(1), *analysis)
+	if err != nil {
+		a.Kill()
+		log.Fatalln(err)
+	}
+
+	diff, err := radareutil.DiffBinaries(a, b, &radareutil.DiffOptions{
+		IncludeUnchanged: *includeUnchanged,
+	})
+	a.Kill()
+	b.Kill()
+	if err != nil {
+		log.Fatalf("failed to diff binaries - %s", err.Error())
+	}
+
+	if *jsonOutput {
+		err = diff.WriteJson(os.Stdout)
+	} else {
+		err = diff.WriteText(os.Stdout)
+	}
+	if err != nil {
+		log.Fatalf("failed to write diff - %s", err.Error())
+	}
+}
+
+func startApi(exePath string, binaryPath string, analysis string) (radareutil.Api, error) {
+	api, err := radareutil.NewCliApi(&radareutil.Radare2Config{
+		ExecutablePath:    exePath,
+		AdditionalCliArgs: []string{binaryPath},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create a CLI API for '%s' - %s", binaryPath, err.Error())
+	}
+
+	err = api.Start()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start CLI API for '%s' - %s", binaryPath, err.Error())
+	}
+
+	if analysis != "" {
+		_, err = api.Execute(analysis)
+		if err != nil {
+			api.Kill()
+			return nil, fmt.Errorf("failed to analyze '%s' - %s", binaryPath, err.Error())
+		}
+	}
+
+	return api, nil
+}
@@ -0,0 +1,23 @@
+package main
+
+import (
+	"os"
+
+	"github.com/stephen-fox/radareutil"
+)
+
+func main() {
+	if !radareutil.IsSandboxLauncher() {
+		os.Stderr.WriteString(`r2sandbox
+
+Applies resource limits, no_new_privs, and seccomp filters to radare2 before
+executing it. It is not meant to be run directly. Instead, set the launcher
+path of a radareutil sandbox configuration to the path of this program.
+`)
+		os.Exit(1)
+	}
+
+	err := radareutil.RunSandboxLauncher()
+	os.Stderr.WriteString("failed to launch radare2 - " + err.Error() + "\n")
+	os.Exit(126)
+}
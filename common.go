@@ -6,8 +6,12 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
+	"os"
 	"os/exec"
+	"strings"
 	"sync"
+	"time"
 )
 
 type Mode string
@@ -47,6 +51,30 @@ type Api interface {
 	ExecuteToBytes(command string) ([]byte, error)
 }
 
+// StreamApi is implemented by Apis that can provide the output of
+// a command as it is produced by radare2. Use ExecuteStream to stream
+// the output of a command using any Api.
+type StreamApi interface {
+	ExecuteStream(command string) (io.ReadCloser, error)
+}
+
+// ExecuteStream executes a command and returns its output as it is
+// produced by radare2 if api implements StreamApi. Otherwise, the
+// command's output is read in full and then returned. The caller must
+// close the returned io.ReadCloser.
+func ExecuteStream(api Api, command string) (io.ReadCloser, error) {
+	if streamer, canStream := api.(StreamApi); canStream {
+		return streamer.ExecuteStream(command)
+	}
+
+	raw, err := api.ExecuteToBytes(command)
+	if err != nil {
+		return nil, err
+	}
+
+	return ioutil.NopCloser(bytes.NewReader(raw)), nil
+}
+
 type Status struct {
 	State State
 }
@@ -74,6 +102,32 @@ type Radare2Config struct {
 	DisableHttpSandbox bool
 	HttpPort           int
 	DetachOnStop       bool
+	Sandbox            *SandboxConfig
+
+	// DebugProgram, if non-nil, starts the program under radare2's
+	// debugger. AdditionalCliArgs must not contain a file to open
+	// when this is set.
+	DebugProgram *DebugProgram
+
+	// Rarun2Profile, if non-nil, starts its program under radare2's
+	// debugger using the profile. The profile is written to a temporary
+	// file that is deleted when radare2 exits. AdditionalCliArgs must
+	// not contain a file to open when this is set.
+	Rarun2Profile *Rarun2Profile
+
+	// CommandTimeout is the maximum amount of time the CLI API waits
+	// for a command to finish. When it is exceeded, the command is
+	// interrupted and an *CommandTimeoutError is returned. Zero means
+	// there is no timeout.
+	CommandTimeout time.Duration
+
+	// WriteMode opens files in write mode ('-w'), which allows
+	// them to be patched.
+	WriteMode bool
+
+	// Project, if non-empty, is the name of a radare2 project to
+	// open when radare2 starts ('-p').
+	Project string
 }
 
 func (o *Radare2Config) Validate() error {
@@ -81,6 +135,56 @@ func (o *Radare2Config) Validate() error {
 		return errors.New("executable path is empty")
 	}
 
+	debugTargets := 0
+	if o.DebugPid > 0 {
+		debugTargets++
+	}
+
+	if o.DebugProgram != nil {
+		debugTargets++
+
+		err := o.DebugProgram.Validate()
+		if err != nil {
+			return err
+		}
+	}
+
+	if o.Rarun2Profile != nil {
+		debugTargets++
+
+		if o.Rarun2Profile.Program == "" {
+			return errors.New("rarun2 profile program is empty")
+		}
+
+		err := o.Rarun2Profile.Validate()
+		if err != nil {
+			return err
+		}
+	}
+
+	if debugTargets > 1 {
+		return errors.New("only one of debug pid, debug program, and rarun2 profile can be set")
+	}
+
+	// The seccomp filter rejects the system calls used to debug
+	// a process, such as ptrace and process_vm_readv.
+	if debugTargets > 0 && o.Sandbox != nil && o.Sandbox.Seccomp {
+		return errors.New("seccomp cannot be used with a debug pid, debug program, or rarun2 profile")
+	}
+
+	if o.Project != "" {
+		err := validateProjectName(o.Project)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Custom arguments replace every argument generated from the
+	// configuration, including the one that enables radare2's sandbox.
+	if o.CustomCliArgs != nil && o.Sandbox != nil && o.Sandbox.Radare2Sandbox {
+		return errors.New("the radare2 sandbox cannot be used with custom cli args")
+	}
+
 	return nil
 }
 
@@ -109,20 +213,46 @@ func (o *Radare2Config) Args(mode Mode) ([]string, error) {
 		return nil, fmt.Errorf("unknown mode '%s'", mode.String())
 	}
 
+	if o.Sandbox != nil && o.Sandbox.Radare2Sandbox {
+		args = append(args, "-e", "cfg.sandbox=true")
+	}
+
+	if o.WriteMode {
+		args = append(args, "-w")
+	}
+
+	if o.Project != "" {
+		args = append(args, "-p", o.Project)
+	}
+
 	if o.DebugPid > 0 {
 		args = append(args, "-d", fmt.Sprintf("%d", o.DebugPid))
 	}
 
+	if o.DebugProgram != nil {
+		args = append(args, "-d")
+		for _, directive := range o.DebugProgram.profile().directives() {
+			args = append(args, "-R", directive)
+		}
+	}
+
+	if o.Rarun2Profile != nil {
+		args = append(args, "-d")
+	}
+
 	if len(o.AdditionalCliArgs) > 0 {
 		args = append(args, o.AdditionalCliArgs...)
 	}
 
+	if o.DebugProgram != nil {
+		args = append(args, o.DebugProgram.Path)
+	} else if o.Rarun2Profile != nil {
+		args = append(args, o.Rarun2Profile.Program)
+	}
+
 	return args, nil
 }
 
-// TODO: stderr. Holding onto stderr will require something
-// constantly read it. Failure to do so will lead to radare2
-// not producing any output.
 type r2Proc struct {
 	config  *Radare2Config
 	mutex   *sync.Mutex
@@ -132,7 +262,9 @@ type r2Proc struct {
 	stdin   io.Writer
 	stdout  *bufio.Reader
 	inter   interruptProcFunc
-	stop    chan func()
+	stop    chan stopRequest
+	cleanup func()
+	exits   []debuggeeExit
 }
 
 func (o *r2Proc) status() Status {
@@ -144,6 +276,19 @@ func (o *r2Proc) status() Status {
 	}
 }
 
+func (o *r2Proc) debuggeeExitStatus(pid int) (int, bool) {
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+
+	for i := len(o.exits) - 1; i >= 0; i-- {
+		if pid <= 0 || o.exits[i].pid == pid {
+			return o.exits[i].status, true
+		}
+	}
+
+	return 0, false
+}
+
 func (o *r2Proc) onStopped() chan StoppedInfo {
 	return o.stopped
 }
@@ -161,14 +306,60 @@ func (o *r2Proc) start(mode Mode) error {
 		return err
 	}
 
-	args, err := o.config.Args(mode)
+	config := o.config
+	if config.Sandbox != nil && config.Sandbox.PrivateWorkingDir {
+		config, err = config.withAbsolutePaths()
+		if err != nil {
+			return err
+		}
+	}
+
+	args, err := config.Args(mode)
 	if err != nil {
 		return err
 	}
 
-	radare := exec.Command(o.config.ExecutablePath, args...)
+	var cleanups []func()
+	cleanup := func() {
+		for _, fn := range cleanups {
+			fn()
+		}
+	}
+	started := false
+	defer func() {
+		if !started {
+			cleanup()
+		}
+	}()
+
+	if config.Rarun2Profile != nil && config.CustomCliArgs == nil {
+		profilePath, err := writeTempRarun2Profile(config.Rarun2Profile)
+		if err != nil {
+			return fmt.Errorf("failed to write rarun2 profile - %s", err.Error())
+		}
+		cleanups = append(cleanups, func() {
+			os.Remove(profilePath)
+		})
+
+		args = append([]string{"-r", profilePath}, args...)
+	}
+
+	radare := exec.Command(config.ExecutablePath, args...)
 	radare.SysProcAttr = radareSysProcAttr()
 
+	if config.Sandbox != nil {
+		err = config.Sandbox.validate(mode)
+		if err != nil {
+			return err
+		}
+
+		sandboxCleanup, err := applySandbox(radare, config.Sandbox)
+		if err != nil {
+			return fmt.Errorf("failed to apply sandbox - %s", err.Error())
+		}
+		cleanups = append(cleanups, sandboxCleanup)
+	}
+
 	stdin, err := radare.StdinPipe()
 	if err != nil {
 		return fmt.Errorf("failed to get stdin pipe - %s", err.Error())
@@ -179,6 +370,13 @@ func (o *r2Proc) start(mode Mode) error {
 		return fmt.Errorf("failed to get stdout pipe - %s", err.Error())
 	}
 
+	// stderr must be read constantly. Otherwise, radare2 will stop
+	// producing output once the pipe's buffer is full.
+	stderr, err := radare.StderrPipe()
+	if err != nil {
+		return fmt.Errorf("failed to get stderr pipe - %s", err.Error())
+	}
+
 	var output *syncBuffer
 	if o.config.SaveOutput {
 		output = newSyncBuffer()
@@ -192,10 +390,14 @@ func (o *r2Proc) start(mode Mode) error {
 		return fmt.Errorf("failed to start radare - %s", err.Error())
 	}
 
+	started = true
 	o.state = Running
 	o.cmd = radare
 	o.stdin = stdin
+	o.cleanup = cleanup
+	o.exits = nil
 
+	go o.readStderr(stderr, output)
 	go o.monitor(output)
 
 	return nil
@@ -229,10 +431,11 @@ func (o *r2Proc) monitor(output *syncBuffer) {
 			o.state = Stopped
 		}
 		defer onDone()
-	case onDone := <-o.stop:
-		o.state = Stopped
+	case req := <-o.stop:
+		o.state = req.state
+		info.err = req.err
 		o.cmd.Process.Kill()
-		defer onDone()
+		defer req.onDone()
 	}
 
 	if output != nil {
@@ -245,6 +448,28 @@ func (o *r2Proc) monitor(output *syncBuffer) {
 	}
 
 	o.cmd = nil
+	o.cleanup()
+}
+
+// readStderr reads radare2's stderr until it is closed, saving it to
+// output if it is non-nil and recording the debuggee exit statuses
+// that radare2 reports.
+func (o *r2Proc) readStderr(stderr io.Reader, output *syncBuffer) {
+	scanner := bufio.NewScanner(stderr)
+	for scanner.Scan() {
+		if output != nil {
+			output.Write(append(scanner.Bytes(), '\n'))
+		}
+
+		if exit, isExit := parseDebuggeeExit(scanner.Text()); isExit {
+			o.mutex.Lock()
+			o.exits = append(o.exits, exit)
+			o.mutex.Unlock()
+		}
+	}
+
+	// Discard anything left if a line was too long to scan.
+	io.Copy(ioutil.Discard, stderr)
 }
 
 func (o *r2Proc) interrupt() error {
@@ -259,6 +484,16 @@ func (o *r2Proc) interrupt() error {
 }
 
 func (o *r2Proc) kill() {
+	o.stopWithState(Stopped, nil)
+}
+
+// abandon kills the radare2 process and marks it as dead. It is used
+// when the process can no longer be communicated with reliably.
+func (o *r2Proc) abandon(err error) {
+	o.stopWithState(Dead, err)
+}
+
+func (o *r2Proc) stopWithState(state State, err error) {
 	o.mutex.Lock()
 
 	if o.state != Running {
@@ -268,14 +503,24 @@ func (o *r2Proc) kill() {
 
 	rejoin := make(chan struct{})
 
-	o.stop <- func() {
-		o.mutex.Unlock()
-		rejoin <- struct{}{}
+	o.stop <- stopRequest{
+		state: state,
+		err:   err,
+		onDone: func() {
+			o.mutex.Unlock()
+			rejoin <- struct{}{}
+		},
 	}
 
 	<-rejoin
 }
 
+type stopRequest struct {
+	state  State
+	err    error
+	onDone func()
+}
+
 type syncBuffer struct {
 	mutex *sync.Mutex
 	buff  *bytes.Buffer
@@ -314,6 +559,12 @@ func newR2Proc(config *Radare2Config) (*r2Proc, error) {
 		state:   Stopped,
 		stopped: make(chan StoppedInfo),
 		inter:   interruptFunc,
-		stop:    make(chan func()),
+		stop:    make(chan stopRequest),
 	}, nil
 }
+
+// quoteCommand quotes a command so that radare2 does not interpret
+// special characters such as ';', '|', '@', and '`' in it.
+func quoteCommand(command string) string {
+	return `"` + strings.Replace(command, `"`, `\"`, -1) + `"`
+}
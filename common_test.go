@@ -0,0 +1,36 @@
+package radareutil
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// scriptedApi is an Api that responds to commands with canned output.
+// Commands without output fail.
+type scriptedApi struct {
+	Api
+	outputs map[string]string
+}
+
+func (o *scriptedApi) Execute(command string) (string, error) {
+	output, hasOutput := o.outputs[command]
+	if !hasOutput {
+		return "", fmt.Errorf("unexpected command '%s'", command)
+	}
+
+	return output, nil
+}
+
+func (o *scriptedApi) ExecuteToBytes(command string) ([]byte, error) {
+	output, err := o.Execute(command)
+	return []byte(output), err
+}
+
+func (o *scriptedApi) ExecuteToJson(command string, pointer interface{}) error {
+	output, err := o.Execute(command)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal([]byte(output), pointer)
+}
@@ -0,0 +1,201 @@
+package radareutil
+
+import (
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	registerNameRegex = regexp.MustCompile(`^[a-zA-Z0-9_.]+$`)
+)
+
+// Breakpoint is a debugger breakpoint as reported by 'dbj'.
+type Breakpoint struct {
+	Addr      uint64 `json:"addr"`
+	Size      int    `json:"size"`
+	Perm      string `json:"perm"`
+	Hardware  bool   `json:"hw"`
+	Enabled   bool   `json:"enabled"`
+	Condition string `json:"cond"`
+}
+
+// StopReason describes why the debuggee stopped, as reported by 'dij'.
+type StopReason struct {
+	// Type is the kind of event that stopped the debuggee,
+	// such as "breakpoint", "signal", or "exit-pid".
+	Type         string `json:"type"`
+	Signal       string `json:"signal"`
+	SignalNumber int    `json:"signum"`
+	Addr         uint64 `json:"addr"`
+	InBreakpoint bool   `json:"inbp"`
+	Pid          int    `json:"pid"`
+	Tid          int    `json:"tid"`
+}
+
+// StackFrame is a frame in a backtrace as reported by 'dbtj'.
+type StackFrame struct {
+	Index        int    `json:"idx"`
+	Pc           uint64 `json:"pc"`
+	Sp           uint64 `json:"sp"`
+	FrameSize    uint64 `json:"frame_size"`
+	FunctionName string `json:"fname"`
+	Description  string `json:"desc"`
+}
+
+// Debugger drives radare2's debugger. radare2 must be debugging
+// a process, for example by setting Radare2Config.DebugPid.
+type Debugger struct {
+	api Api
+}
+
+// SetBreakpoint sets a software breakpoint at addr ('db').
+func (o *Debugger) SetBreakpoint(addr uint64) error {
+	_, err := o.api.Execute(fmt.Sprintf("db 0x%x", addr))
+	return err
+}
+
+// RemoveBreakpoint removes the breakpoint at addr ('db-').
+func (o *Debugger) RemoveBreakpoint(addr uint64) error {
+	_, err := o.api.Execute(fmt.Sprintf("db- 0x%x", addr))
+	return err
+}
+
+// Breakpoints returns the breakpoints that are currently set ('dbj').
+func (o *Debugger) Breakpoints() ([]Breakpoint, error) {
+	var breakpoints []Breakpoint
+	err := o.api.ExecuteToJson("dbj", &breakpoints)
+	if err != nil {
+		return nil, err
+	}
+
+	return breakpoints, nil
+}
+
+// Continue resumes the debuggee until it stops ('dc').
+func (o *Debugger) Continue() (StopReason, error) {
+	return o.resume("dc")
+}
+
+// Step executes a single instruction ('ds').
+func (o *Debugger) Step() (StopReason, error) {
+	return o.resume("ds")
+}
+
+// StepOver executes a single instruction, stepping over calls ('dso').
+func (o *Debugger) StepOver() (StopReason, error) {
+	return o.resume("dso")
+}
+
+func (o *Debugger) resume(command string) (StopReason, error) {
+	_, err := o.api.Execute(command)
+	if err != nil {
+		return StopReason{}, err
+	}
+
+	return o.StopReason()
+}
+
+// StopReason returns the reason the debuggee last stopped ('dij').
+func (o *Debugger) StopReason() (StopReason, error) {
+	var reason StopReason
+	err := o.api.ExecuteToJson("dij", &reason)
+	if err != nil {
+		return StopReason{}, err
+	}
+
+	return reason, nil
+}
+
+// Registers returns the values of the debuggee's general purpose
+// registers ('drj').
+func (o *Debugger) Registers() (map[string]uint64, error) {
+	var registers map[string]uint64
+	err := o.api.ExecuteToJson("drj", &registers)
+	if err != nil {
+		return nil, err
+	}
+
+	return registers, nil
+}
+
+// SetRegister sets the value of the named register ('dr name=value').
+func (o *Debugger) SetRegister(name string, value uint64) error {
+	err := validateRegisterName(name)
+	if err != nil {
+		return err
+	}
+
+	_, err = o.api.Execute(fmt.Sprintf("dr %s=0x%x", name, value))
+	return err
+}
+
+// ReadMemory reads size bytes of the debuggee's memory at addr ('p8').
+func (o *Debugger) ReadMemory(addr uint64, size int) ([]byte, error) {
+	return readMemory(o.api, addr, size)
+}
+
+// WriteMemory writes data to the debuggee's memory at addr ('wx').
+func (o *Debugger) WriteMemory(addr uint64, data []byte) error {
+	return writeMemory(o.api, addr, data)
+}
+
+// Backtrace returns the debuggee's current call stack ('dbtj').
+func (o *Debugger) Backtrace() ([]StackFrame, error) {
+	var frames []StackFrame
+	err := o.api.ExecuteToJson("dbtj", &frames)
+	if err != nil {
+		return nil, err
+	}
+
+	return frames, nil
+}
+
+// NewDebugger returns a Debugger for the provided Api.
+func NewDebugger(api Api) *Debugger {
+	return &Debugger{
+		api: api,
+	}
+}
+
+// readMemory reads size bytes at addr ('p8').
+func readMemory(api Api, addr uint64, size int) ([]byte, error) {
+	if size <= 0 {
+		return nil, nil
+	}
+
+	output, err := api.Execute(fmt.Sprintf("p8 %d @ 0x%x", size, addr))
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := hex.DecodeString(strings.Join(strings.Fields(output), ""))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode memory at 0x%x - %s", addr, err.Error())
+	}
+
+	if len(data) != size {
+		return nil, fmt.Errorf("read %d bytes at 0x%x, expected %d", len(data), addr, size)
+	}
+
+	return data, nil
+}
+
+// writeMemory writes data at addr ('wx').
+func writeMemory(api Api, addr uint64, data []byte) error {
+	if len(data) == 0 {
+		return nil
+	}
+
+	_, err := api.Execute(fmt.Sprintf("wx %s @ 0x%x", hex.EncodeToString(data), addr))
+	return err
+}
+
+func validateRegisterName(name string) error {
+	if !registerNameRegex.MatchString(name) {
+		return fmt.Errorf("invalid register name '%s' - it may only contain letters, numbers, '_', and '.'", name)
+	}
+
+	return nil
+}
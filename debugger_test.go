@@ -0,0 +1,212 @@
+package radareutil
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// fakeRadare2Debugger is a fake radare2 that is debugging a process
+// stopped at 0x1000. 'dc' continues to the breakpoint set with 'db'.
+const fakeRadare2Debugger = `#!/bin/sh
+bp=
+pc=$((0x1000))
+printf '\000'
+while IFS= read -r line; do
+	case "$line" in
+	"db- "*)
+		bp=
+		;;
+	"db "*)
+		bp=$((${line#db }))
+		;;
+	dbj)
+		if [ -n "$bp" ]; then
+			printf '[{"addr":%d,"size":1,"perm":"--x","hw":false,"trace":false,"enabled":true,"valid":true,"data":"","cond":""}]\n' "$bp"
+		else
+			printf '[]\n'
+		fi
+		;;
+	dc)
+		if [ -n "$bp" ]; then
+			pc=$bp
+			echo "hit breakpoint at: 0x$(printf '%x' "$pc")"
+		fi
+		;;
+	dij)
+		printf '{"type":"breakpoint","signal":"SIGTRAP","signum":5,"addr":%d,"inbp":true,"pid":1234,"tid":1234,"stopaddr":%d,"cwd":"/","cmdline":"/bin/true"}\n' "$pc" "$pc"
+		;;
+	drj)
+		printf '{"rax":0,"rsp":8192,"rip":%d,"rflags":582}\n' "$pc"
+		;;
+	"dr rax="*)
+		;;
+	"p8 4 @ 0x2000")
+		echo 90c3cc00
+		;;
+	dbtj)
+		printf '[{"idx":0,"pc":%d,"sp":8192,"frame_size":0,"fname":"main","desc":"main+0"},{"idx":1,"pc":4352,"sp":8208,"frame_size":16,"fname":"_start","desc":"_start+12"}]\n' "$pc"
+		;;
+	esac
+	printf '\000'
+done
+`
+
+func TestDebugger(t *testing.T) {
+	api, cleanup := startFakeCliApi(t, fakeRadare2Debugger, 10*time.Second)
+	defer cleanup()
+
+	debugger := NewDebugger(api)
+
+	err := debugger.SetBreakpoint(0x1040)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	breakpoints, err := debugger.Breakpoints()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectedBreakpoints := []Breakpoint{
+		{Addr: 0x1040, Size: 1, Perm: "--x", Enabled: true},
+	}
+	if !reflect.DeepEqual(breakpoints, expectedBreakpoints) {
+		t.Errorf("breakpoints are %+v - expected %+v", breakpoints, expectedBreakpoints)
+	}
+
+	reason, err := debugger.Continue()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectedReason := StopReason{
+		Type:         "breakpoint",
+		Signal:       "SIGTRAP",
+		SignalNumber: 5,
+		Addr:         0x1040,
+		InBreakpoint: true,
+		Pid:          1234,
+		Tid:          1234,
+	}
+	if reason != expectedReason {
+		t.Errorf("stop reason is %+v - expected %+v", reason, expectedReason)
+	}
+
+	registers, err := debugger.Registers()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if registers["rip"] != 0x1040 || registers["rsp"] != 0x2000 || len(registers) != 4 {
+		t.Errorf("unexpected registers %v", registers)
+	}
+
+	err = debugger.SetRegister("rax", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"", "rax;!id", "rax @ 0x10", "rax=1"} {
+		err = debugger.SetRegister(name, 1)
+		if err == nil {
+			t.Errorf("setting invalid register '%s' succeeded", name)
+		}
+	}
+
+	memory, err := debugger.ReadMemory(0x2000, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(memory, []byte{0x90, 0xc3, 0xcc, 0x00}) {
+		t.Errorf("memory is %x - expected 90c3cc00", memory)
+	}
+
+	_, err = debugger.ReadMemory(0x3000, 4)
+	if err == nil {
+		t.Error("reading memory that produced no output did not fail")
+	}
+
+	frames, err := debugger.Backtrace()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectedFrames := []StackFrame{
+		{Index: 0, Pc: 0x1040, Sp: 0x2000, FunctionName: "main", Description: "main+0"},
+		{Index: 1, Pc: 0x1100, Sp: 0x2010, FrameSize: 16, FunctionName: "_start", Description: "_start+12"},
+	}
+	if !reflect.DeepEqual(frames, expectedFrames) {
+		t.Errorf("backtrace is %+v - expected %+v", frames, expectedFrames)
+	}
+
+	err = debugger.RemoveBreakpoint(0x1040)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	breakpoints, err = debugger.Breakpoints()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(breakpoints) != 0 {
+		t.Errorf("breakpoints are %+v after removing the breakpoint", breakpoints)
+	}
+}
+
+// fakeRadare2Debuggee is a fake radare2 debugging process 4321, which
+// exits with status 3 when it is continued.
+const fakeRadare2Debuggee = `#!/bin/sh
+reason=breakpoint
+printf '\000'
+while IFS= read -r line; do
+	case "$line" in
+	dc)
+		echo '==> Process finished' >&2
+		echo '(4321) Process exited with status=0x3' >&2
+		reason=exit-pid
+		;;
+	dij)
+		printf '{"type":"%s","signal":"SIGTRAP","signum":5,"addr":4096,"inbp":false,"pid":4321,"tid":4321}\n' "$reason"
+		;;
+	esac
+	printf '\000'
+done
+`
+
+func TestDebuggee(t *testing.T) {
+	api, cleanup := startFakeCliApi(t, fakeRadare2Debuggee, 10*time.Second)
+	defer cleanup()
+
+	status, err := Debuggee(api)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if status != (DebuggeeStatus{Pid: 4321}) {
+		t.Errorf("debuggee status is %+v before continuing", status)
+	}
+
+	_, err = NewDebugger(api).Continue()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	status, err = Debuggee(api)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := DebuggeeStatus{
+		Pid:             4321,
+		Exited:          true,
+		ExitStatus:      3,
+		ExitStatusKnown: true,
+	}
+	if status != expected {
+		t.Errorf("debuggee status is %+v - expected %+v", status, expected)
+	}
+}
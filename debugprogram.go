@@ -0,0 +1,148 @@
+package radareutil
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+const (
+	// debuggeeExitStatusWait is the amount of time to wait for radare2's
+	// report of a debuggee's exit status to be read from its stderr.
+	debuggeeExitStatusWait = 1 * time.Second
+)
+
+var (
+	debuggeeExitedRegex = regexp.MustCompile(`\((\d+)\) Process (?:terminated|exited) with status\s*=?\s*(0x[0-9a-fA-F]+|-?\d+)`)
+)
+
+// DebugProgram is a program that radare2 starts and debugs.
+type DebugProgram struct {
+	// Path is the path to the program.
+	Path string
+
+	// Args are the arguments passed to the program.
+	Args []string
+
+	// Env is a list of additional environment variables
+	// in the form "KEY=value".
+	Env []string
+
+	// Stdin, Stdout, and Stderr are optional file paths that the
+	// program's standard input, output, and error are redirected to.
+	Stdin  string
+	Stdout string
+	Stderr string
+}
+
+func (o *DebugProgram) Validate() error {
+	if o.Path == "" {
+		return errors.New("debug program path is empty")
+	}
+
+	return o.profile().Validate()
+}
+
+// profile returns a rarun2 profile that launches the program. The
+// program itself is omitted because radare2 provides it.
+func (o *DebugProgram) profile() *Rarun2Profile {
+	return &Rarun2Profile{
+		Args:   o.Args,
+		Env:    o.Env,
+		Stdin:  o.Stdin,
+		Stdout: o.Stdout,
+		Stderr: o.Stderr,
+	}
+}
+
+// DebuggeeStatus describes the process radare2 is debugging.
+type DebuggeeStatus struct {
+	// Pid is the process ID of the debuggee.
+	Pid int
+
+	// Exited is true if the debuggee has exited.
+	Exited bool
+
+	// ExitStatus is the exit status of the debuggee. It is only valid
+	// if ExitStatusKnown is true. radare2 only reports exit statuses
+	// in messages written to stderr, which are not available when
+	// using an Api that does not read them, such as a policy Api
+	// wrapping a third-party implementation.
+	ExitStatus      int
+	ExitStatusKnown bool
+}
+
+// Debuggee returns the status of the process radare2 is debugging. The
+// process ID and whether it has exited are determined using 'dij'.
+func Debuggee(api Api) (DebuggeeStatus, error) {
+	reason, err := NewDebugger(api).StopReason()
+	if err != nil {
+		return DebuggeeStatus{}, fmt.Errorf("failed to get debuggee status - %s", err.Error())
+	}
+
+	status := DebuggeeStatus{
+		Pid:    reason.Pid,
+		Exited: reason.Type == "exit-pid" || reason.Type == "dead",
+	}
+
+	if !status.Exited {
+		return status, nil
+	}
+
+	reporter, isReporter := api.(debuggeeExitReporter)
+	if !isReporter {
+		return status, nil
+	}
+
+	// radare2 writes the exit status to stderr, which is read
+	// independently of the output of commands.
+	deadline := time.Now().Add(debuggeeExitStatusWait)
+	for {
+		status.ExitStatus, status.ExitStatusKnown = reporter.debuggeeExitStatus(status.Pid)
+		if status.ExitStatusKnown || time.Now().After(deadline) {
+			return status, nil
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// debuggeeExitReporter is implemented by Apis that keep track of the
+// debuggee exit statuses that radare2 reports on stderr.
+type debuggeeExitReporter interface {
+	// debuggeeExitStatus returns the exit status of the process.
+	// If pid is not positive, the most recent exit status is returned.
+	debuggeeExitStatus(pid int) (int, bool)
+}
+
+// debuggeeExit is an exit status reported by radare2.
+type debuggeeExit struct {
+	pid    int
+	status int
+}
+
+// parseDebuggeeExit parses a line that radare2 wrote to stderr
+// reporting that the debuggee exited.
+func parseDebuggeeExit(line string) (debuggeeExit, bool) {
+	match := debuggeeExitedRegex.FindStringSubmatch(line)
+	if match == nil {
+		return debuggeeExit{}, false
+	}
+
+	pid, err := strconv.Atoi(match[1])
+	if err != nil {
+		return debuggeeExit{}, false
+	}
+
+	status, err := strconv.ParseInt(match[2], 0, 64)
+	if err != nil {
+		return debuggeeExit{}, false
+	}
+
+	return debuggeeExit{
+		pid:    pid,
+		status: int(status),
+	}, true
+}
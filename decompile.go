@@ -0,0 +1,252 @@
+package radareutil
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+type DecompilerBackend string
+
+func (o DecompilerBackend) String() string {
+	return string(o)
+}
+
+const (
+	// DecompilerGhidra is the r2ghidra plugin ('pdg').
+	DecompilerGhidra DecompilerBackend = "r2ghidra"
+
+	// DecompilerR2dec is the r2dec plugin ('pdd').
+	DecompilerR2dec DecompilerBackend = "r2dec"
+
+	// DecompilerPdc is radare2's built-in pseudo code output ('pdc').
+	// It is always available.
+	DecompilerPdc DecompilerBackend = "pdc"
+)
+
+// DecompiledLine is a line of decompiled code.
+type DecompiledLine struct {
+	Text string
+
+	// Addr is the lowest address of the instructions that the line
+	// was decompiled from. It is only valid if HasAddr is true.
+	Addr    uint64
+	HasAddr bool
+}
+
+// Decompilation is the decompiled code of a function.
+type Decompilation struct {
+	Backend DecompilerBackend
+	Addr    uint64
+	Code    string
+
+	// Lines are the lines of Code. Only backends that provide JSON
+	// output, such as DecompilerGhidra and DecompilerR2dec, map lines
+	// to addresses.
+	Lines []DecompiledLine
+}
+
+type rawGhidraDecompilation struct {
+	Code        string `json:"code"`
+	Annotations []struct {
+		Start  int    `json:"start"`
+		End    int    `json:"end"`
+		Type   string `json:"type"`
+		Offset uint64 `json:"offset"`
+	} `json:"annotations"`
+	Errors []string `json:"errors"`
+}
+
+type rawR2decDecompilation struct {
+	Lines []struct {
+		Str    string  `json:"str"`
+		Offset *uint64 `json:"offset"`
+	} `json:"lines"`
+	Errors []string `json:"errors"`
+}
+
+// DecompilerBackends returns the decompilers that are available, in
+// order of preference. Decompiler plugins are detected using the list
+// of core plugins ('Lcj'). DecompilerPdc is always the last backend.
+// If the plugins cannot be listed because 'Lcj' is not allowed by
+// a Policy or is not supported by radare2, only DecompilerPdc is
+// returned. Other failures are returned as an error.
+func DecompilerBackends(api Api) ([]DecompilerBackend, error) {
+	output, err := api.ExecuteToBytes("Lcj")
+	if err != nil {
+		if _, isPolicyErr := err.(*PolicyError); isPolicyErr {
+			return []DecompilerBackend{DecompilerPdc}, nil
+		}
+		return nil, fmt.Errorf("failed to list core plugins - %s", err.Error())
+	}
+
+	// radare2 writes an error to stderr and produces no output
+	// if a command is unknown.
+	if len(bytes.TrimSpace(output)) == 0 {
+		return []DecompilerBackend{DecompilerPdc}, nil
+	}
+
+	var plugins []map[string]interface{}
+	err = json.Unmarshal(output, &plugins)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse core plugins - %s", err.Error())
+	}
+
+	names := make(map[string]bool)
+	for _, plugin := range plugins {
+		for key, value := range plugin {
+			name, isString := value.(string)
+			if isString && strings.EqualFold(key, "name") {
+				names[strings.ToLower(name)] = true
+			}
+		}
+	}
+
+	var backends []DecompilerBackend
+	for _, backend := range []DecompilerBackend{DecompilerGhidra, DecompilerR2dec} {
+		if names[backend.String()] {
+			backends = append(backends, backend)
+		}
+	}
+
+	return append(backends, DecompilerPdc), nil
+}
+
+// Decompile decompiles the function at addr using the most preferred
+// backend returned by DecompilerBackends. The function must be analyzed
+// beforehand, for example by executing 'af' or 'aaa'.
+func Decompile(api Api, addr uint64) (*Decompilation, error) {
+	backends, err := DecompilerBackends(api)
+	if err != nil {
+		return nil, err
+	}
+
+	return DecompileWith(api, backends[0], addr)
+}
+
+// DecompileWith decompiles the function at addr using the specified backend.
+func DecompileWith(api Api, backend DecompilerBackend, addr uint64) (*Decompilation, error) {
+	var decompilation *Decompilation
+	var err error
+
+	switch backend {
+	case DecompilerGhidra:
+		decompilation, err = decompileGhidra(api, addr)
+	case DecompilerR2dec:
+		decompilation, err = decompileR2dec(api, addr)
+	case DecompilerPdc:
+		decompilation, err = decompilePdc(api, addr)
+	default:
+		return nil, fmt.Errorf("unknown decompiler backend '%s'", backend.String())
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompile 0x%x using %s - %s", addr, backend.String(), err.Error())
+	}
+
+	decompilation.Backend = backend
+	decompilation.Addr = addr
+
+	return decompilation, nil
+}
+
+func decompileGhidra(api Api, addr uint64) (*Decompilation, error) {
+	var raw rawGhidraDecompilation
+	err := api.ExecuteToJson(fmt.Sprintf("pdgj @ 0x%x", addr), &raw)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(raw.Errors) > 0 && raw.Code == "" {
+		return nil, errors.New(strings.Join(raw.Errors, ", "))
+	}
+
+	texts := strings.Split(strings.TrimRight(raw.Code, "\n"), "\n")
+	lines := make([]DecompiledLine, len(texts))
+
+	lineStarts := make([]int, len(texts))
+	start := 0
+	for i, text := range texts {
+		lines[i].Text = text
+		lineStarts[i] = start
+		start += len(text) + 1
+	}
+
+	// Annotations refer to ranges of characters in the code. Each
+	// offset annotation is mapped to the line it starts on.
+	for _, annotation := range raw.Annotations {
+		if annotation.Type != "offset" {
+			continue
+		}
+
+		i := sort.Search(len(lineStarts), func(i int) bool {
+			return lineStarts[i] > annotation.Start
+		}) - 1
+		if i < 0 {
+			continue
+		}
+
+		if !lines[i].HasAddr || annotation.Offset < lines[i].Addr {
+			lines[i].Addr = annotation.Offset
+			lines[i].HasAddr = true
+		}
+	}
+
+	return &Decompilation{
+		Code:  raw.Code,
+		Lines: lines,
+	}, nil
+}
+
+func decompileR2dec(api Api, addr uint64) (*Decompilation, error) {
+	var raw rawR2decDecompilation
+	err := api.ExecuteToJson(fmt.Sprintf("pddj @ 0x%x", addr), &raw)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(raw.Errors) > 0 && len(raw.Lines) == 0 {
+		return nil, errors.New(strings.Join(raw.Errors, ", "))
+	}
+
+	lines := make([]DecompiledLine, len(raw.Lines))
+	texts := make([]string, len(raw.Lines))
+	for i, line := range raw.Lines {
+		texts[i] = line.Str
+		lines[i].Text = line.Str
+
+		if line.Offset != nil {
+			lines[i].Addr = *line.Offset
+			lines[i].HasAddr = true
+		}
+	}
+
+	return &Decompilation{
+		Code:  strings.Join(texts, "\n") + "\n",
+		Lines: lines,
+	}, nil
+}
+
+func decompilePdc(api Api, addr uint64) (*Decompilation, error) {
+	code, err := api.Execute(fmt.Sprintf("pdc @ 0x%x", addr))
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.TrimSpace(code) == "" {
+		return nil, errors.New("pdc produced no output")
+	}
+
+	texts := strings.Split(strings.TrimRight(code, "\n"), "\n")
+	lines := make([]DecompiledLine, len(texts))
+	for i, text := range texts {
+		lines[i].Text = text
+	}
+
+	return &Decompilation{
+		Code:  code,
+		Lines: lines,
+	}, nil
+}
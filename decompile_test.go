@@ -0,0 +1,83 @@
+package radareutil
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDecompilerBackends(t *testing.T) {
+	api := &scriptedApi{
+		outputs: map[string]string{
+			"Lcj": `[{"Name":"r2ghidra","Description":"Ghidra decompiler"},{"Name":"java","Description":"Java"}]`,
+		},
+	}
+
+	backends, err := DecompilerBackends(api)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []DecompilerBackend{DecompilerGhidra, DecompilerPdc}
+	if !reflect.DeepEqual(backends, expected) {
+		t.Errorf("backends are %v - expected %v", backends, expected)
+	}
+}
+
+func TestDecompilerBackendsUnknownCommand(t *testing.T) {
+	api := &scriptedApi{
+		outputs: map[string]string{
+			"Lcj": "\n",
+		},
+	}
+
+	backends, err := DecompilerBackends(api)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(backends, []DecompilerBackend{DecompilerPdc}) {
+		t.Errorf("backends are %v - expected only %s", backends, DecompilerPdc)
+	}
+}
+
+func TestDecompileListPluginsFailure(t *testing.T) {
+	for _, api := range []Api{
+		&scriptedApi{},
+		&scriptedApi{
+			outputs: map[string]string{
+				"Lcj":          "[{",
+				"pdc @ 0x1000": "int main() {\n}\n",
+			},
+		},
+	} {
+		_, err := Decompile(api, 0x1000)
+		if err == nil {
+			t.Error("decompiling succeeded after failing to list core plugins")
+		}
+	}
+}
+
+func TestDecompileWithoutPluginList(t *testing.T) {
+	api, err := NewPolicyApi(&scriptedApi{
+		outputs: map[string]string{
+			"Lcj":          `[{"Name":"r2ghidra"}]`,
+			"pdc @ 0x1000": "int main() {\n    return 0;\n}\n",
+		},
+	}, NewReadOnlyPolicy())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decompilation, err := Decompile(api, 0x1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if decompilation.Backend != DecompilerPdc {
+		t.Errorf("backend is %s - expected %s", decompilation.Backend, DecompilerPdc)
+	}
+
+	if len(decompilation.Lines) != 3 || decompilation.Lines[1].Text != "    return 0;" {
+		t.Errorf("unexpected lines %+v", decompilation.Lines)
+	}
+}
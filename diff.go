@@ -0,0 +1,657 @@
+package radareutil
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+var (
+	diffHexRegex      = regexp.MustCompile(`0x[0-9a-fA-F]+`)
+	diffAutoNameRegex = regexp.MustCompile(`\b(fcn|sub|loc)\.[0-9a-fA-F]+\b`)
+
+	// diffAutoFunctionNameRegex matches function names generated by
+	// radare2's analysis, such as "fcn.00401000", "sub.printf_401000",
+	// "loc.00401000", "entry0", and "entry.init0".
+	diffAutoFunctionNameRegex = regexp.MustCompile(`^((fcn|sub|loc)\.|entry([0-9]+$|\.))`)
+)
+
+type DiffStatus string
+
+func (o DiffStatus) String() string {
+	return string(o)
+}
+
+const (
+	DiffAdded     DiffStatus = "added"
+	DiffRemoved   DiffStatus = "removed"
+	DiffChanged   DiffStatus = "changed"
+	DiffUnchanged DiffStatus = "unchanged"
+)
+
+// FunctionMatch describes how a function in one binary was matched
+// to a function in another.
+type FunctionMatch string
+
+func (o FunctionMatch) String() string {
+	return string(o)
+}
+
+const (
+	// MatchName matches functions with the same name. Names generated
+	// by radare2's analysis, such as "fcn.00401000", "sub.printf_401000",
+	// "loc.00401000", and "entry0", are not matched.
+	MatchName FunctionMatch = "name"
+
+	// MatchHash matches functions whose normalized instructions have
+	// the same hash. Instructions are normalized by replacing addresses,
+	// such as branch targets, with symbolic values so that functions
+	// which only moved, for example after being relinked, are equal.
+	MatchHash FunctionMatch = "hash"
+
+	// MatchSignature matches functions with the same signature, such
+	// as "int parse (char *buf, int len);", ignoring the function name
+	// so that renamed functions are matched.
+	MatchSignature FunctionMatch = "signature"
+
+	// MatchStructure matches functions with the same size, number of
+	// basic blocks, number of instructions, and cyclomatic complexity.
+	MatchStructure FunctionMatch = "structure"
+)
+
+// DiffBasicBlock is a basic block in a BasicBlockDiff.
+type DiffBasicBlock struct {
+	Addr         uint64   `json:"addr"`
+	Size         uint64   `json:"size"`
+	Instructions []string `json:"instructions"`
+
+	// text is the basic block formatted by PdbToBasicBlockText.
+	text string
+
+	// key is the basic block's normalized instructions.
+	key string
+}
+
+// BasicBlockDiff compares a basic block of a function in binary A to
+// the corresponding basic block in binary B. A is nil if the basic block
+// was added, and B is nil if it was removed.
+type BasicBlockDiff struct {
+	Status DiffStatus      `json:"status"`
+	A      *DiffBasicBlock `json:"a,omitempty"`
+	B      *DiffBasicBlock `json:"b,omitempty"`
+}
+
+// FunctionDiff compares a function in binary A to the corresponding
+// function in binary B. A is nil if the function was added, and B is
+// nil if it was removed.
+type FunctionDiff struct {
+	Status DiffStatus    `json:"status"`
+	Match  FunctionMatch `json:"match,omitempty"`
+	A      *Function     `json:"a,omitempty"`
+	B      *Function     `json:"b,omitempty"`
+
+	// BasicBlocks is only set for changed functions.
+	BasicBlocks []BasicBlockDiff `json:"basic_blocks,omitempty"`
+}
+
+// Name returns the name of the function in binary B, or the name of
+// the function in binary A if it was removed.
+func (o FunctionDiff) Name() string {
+	if o.B != nil {
+		return o.B.Name
+	}
+
+	if o.A != nil {
+		return o.A.Name
+	}
+
+	return ""
+}
+
+// BinaryDiff is the result of DiffBinaries.
+type BinaryDiff struct {
+	Functions []FunctionDiff `json:"functions"`
+}
+
+// Filter returns the function diffs with the specified status.
+func (o *BinaryDiff) Filter(status DiffStatus) []FunctionDiff {
+	var diffs []FunctionDiff
+	for _, diff := range o.Functions {
+		if diff.Status == status {
+			diffs = append(diffs, diff)
+		}
+	}
+
+	return diffs
+}
+
+// WriteJson writes the diff to w as JSON.
+func (o *BinaryDiff) WriteJson(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(o)
+}
+
+// WriteText writes a summary of the diff to w, followed by the basic
+// blocks of each changed function. Basic blocks are formatted by
+// PdbToBasicBlockText, with binary A on the left and binary B on
+// the right.
+func (o *BinaryDiff) WriteText(w io.Writer) error {
+	buff := bytes.NewBuffer(nil)
+
+	for _, status := range []DiffStatus{DiffAdded, DiffRemoved, DiffChanged, DiffUnchanged} {
+		diffs := o.Filter(status)
+		if len(diffs) == 0 {
+			continue
+		}
+
+		buff.WriteString(fmt.Sprintf("%s functions (%d):\n", status.String(), len(diffs)))
+		for _, diff := range diffs {
+			buff.WriteString(fmt.Sprintf("  %s%s\n", diff.Name(), functionDiffAddrs(diff)))
+		}
+		buff.WriteString("\n")
+	}
+
+	for _, diff := range o.Filter(DiffChanged) {
+		buff.WriteString(fmt.Sprintf("%s%s:\n", diff.Name(), functionDiffAddrs(diff)))
+
+		for _, block := range diff.BasicBlocks {
+			if block.Status == DiffUnchanged {
+				continue
+			}
+
+			var left, right string
+			if block.A != nil {
+				left = block.A.text
+			}
+			if block.B != nil {
+				right = block.B.text
+			}
+
+			buff.WriteString(fmt.Sprintf("%s basic block:\n", block.Status.String()))
+			buff.WriteString(sideBySideText(left, right, "   "))
+			buff.WriteString("\n")
+		}
+	}
+
+	_, err := w.Write(buff.Bytes())
+	return err
+}
+
+func functionDiffAddrs(diff FunctionDiff) string {
+	switch {
+	case diff.A != nil && diff.B != nil:
+		return fmt.Sprintf(" (0x%x -> 0x%x)", diff.A.Addr, diff.B.Addr)
+	case diff.A != nil:
+		return fmt.Sprintf(" (0x%x)", diff.A.Addr)
+	case diff.B != nil:
+		return fmt.Sprintf(" (0x%x)", diff.B.Addr)
+	default:
+		return ""
+	}
+}
+
+// sideBySideText joins the lines of left and right, padding the lines
+// of left so that right is aligned.
+func sideBySideText(left string, right string, gap string) string {
+	var leftLines, rightLines []string
+	if left != "" {
+		leftLines = strings.Split(left, "\n")
+	}
+	if right != "" {
+		rightLines = strings.Split(right, "\n")
+	}
+
+	width := 0
+	for _, line := range leftLines {
+		if lineWidth := utf8.RuneCountInString(line); lineWidth > width {
+			width = lineWidth
+		}
+	}
+
+	numLines := len(leftLines)
+	if len(rightLines) > numLines {
+		numLines = len(rightLines)
+	}
+
+	buff := bytes.NewBuffer(nil)
+	for i := 0; i < numLines; i++ {
+		var leftLine, rightLine string
+		if i < len(leftLines) {
+			leftLine = leftLines[i]
+		}
+		if i < len(rightLines) {
+			rightLine = rightLines[i]
+		}
+
+		buff.WriteString(leftLine)
+		if rightLine != "" {
+			buff.WriteString(strings.Repeat(" ", width-utf8.RuneCountInString(leftLine)))
+			buff.WriteString(gap)
+			buff.WriteString(rightLine)
+		}
+		buff.WriteString("\n")
+	}
+
+	return buff.String()
+}
+
+// DiffOptions configures DiffBinaries.
+type DiffOptions struct {
+	// IncludeUnchanged includes unchanged functions in the diff.
+	IncludeUnchanged bool
+}
+
+// DiffBinaries compares the functions of the binaries that two radare2
+// sessions have open. Both binaries must be analyzed beforehand, for
+// example by executing 'aaa'. Functions are matched by name, then by
+// the hash of their normalized instructions, then by their signature,
+// and then by their structure. Matched functions are changed if their normalized
+// instructions differ. A nil options is equivalent to an empty
+// DiffOptions.
+func DiffBinaries(a Api, b Api, options *DiffOptions) (*BinaryDiff, error) {
+	if options == nil {
+		options = &DiffOptions{}
+	}
+
+	sideA, err := newDiffSide(a)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get functions of binary A - %s", err.Error())
+	}
+
+	sideB, err := newDiffSide(b)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get functions of binary B - %s", err.Error())
+	}
+
+	var pairs []diffPair
+
+	pairs = append(pairs, matchUnique(sideA, sideB, MatchName, func(side *diffSide, i int) string {
+		name := side.functions[i].Name
+		if diffAutoFunctionNameRegex.MatchString(name) {
+			return ""
+		}
+		return name
+	})...)
+
+	pairs = append(pairs, matchUnique(sideA, sideB, MatchHash, func(side *diffSide, i int) string {
+		return side.hashes[i]
+	})...)
+
+	pairs = append(pairs, matchUnique(sideA, sideB, MatchSignature, func(side *diffSide, i int) string {
+		function := side.functions[i]
+		if function.Signature == "" {
+			return ""
+		}
+		return strings.Replace(function.Signature, function.Name, "", 1)
+	})...)
+
+	pairs = append(pairs, matchUnique(sideA, sideB, MatchStructure, func(side *diffSide, i int) string {
+		function := side.functions[i]
+		return fmt.Sprintf("%d/%d/%d/%d", function.Size, function.BasicBlocks,
+			function.Instructions, function.Complexity)
+	})...)
+
+	result := &BinaryDiff{}
+
+	for _, pair := range pairs {
+		functionA := sideA.functions[pair.a]
+		functionB := sideB.functions[pair.b]
+
+		diff := FunctionDiff{
+			Status: DiffUnchanged,
+			Match:  pair.match,
+			A:      &functionA,
+			B:      &functionB,
+		}
+
+		if sideA.hashes[pair.a] != sideB.hashes[pair.b] {
+			diff.Status = DiffChanged
+
+			diff.BasicBlocks, err = diffBasicBlocks(a, functionA, b, functionB)
+			if err != nil {
+				return nil, fmt.Errorf("failed to diff basic blocks of '%s' - %s", functionB.Name, err.Error())
+			}
+		}
+
+		if diff.Status == DiffUnchanged && !options.IncludeUnchanged {
+			continue
+		}
+
+		result.Functions = append(result.Functions, diff)
+	}
+
+	for i := range sideA.functions {
+		if !sideA.matched[i] {
+			function := sideA.functions[i]
+			result.Functions = append(result.Functions, FunctionDiff{
+				Status: DiffRemoved,
+				A:      &function,
+			})
+		}
+	}
+
+	for i := range sideB.functions {
+		if !sideB.matched[i] {
+			function := sideB.functions[i]
+			result.Functions = append(result.Functions, FunctionDiff{
+				Status: DiffAdded,
+				B:      &function,
+			})
+		}
+	}
+
+	sort.SliceStable(result.Functions, func(i int, j int) bool {
+		return result.Functions[i].Name() < result.Functions[j].Name()
+	})
+
+	return result, nil
+}
+
+type diffPair struct {
+	a     int
+	b     int
+	match FunctionMatch
+}
+
+// diffSide contains the functions of one of the binaries being diffed.
+type diffSide struct {
+	functions []Function
+	hashes    []string
+	matched   []bool
+}
+
+func newDiffSide(api Api) (*diffSide, error) {
+	functions, err := Functions(api)
+	if err != nil {
+		return nil, err
+	}
+
+	hashes := make([]string, len(functions))
+	for i, function := range functions {
+		if function.Size == 0 {
+			continue
+		}
+
+		hashes[i], err = functionHash(api, function)
+		if err != nil {
+			return nil, fmt.Errorf("failed to hash function '%s' - %s", function.Name, err.Error())
+		}
+	}
+
+	return &diffSide{
+		functions: functions,
+		hashes:    hashes,
+		matched:   make([]bool, len(functions)),
+	}, nil
+}
+
+// unmatchedByKey returns the indexes of the unmatched functions
+// grouped by key. Functions with an empty key are omitted.
+func (o *diffSide) unmatchedByKey(key func(*diffSide, int) string) map[string][]int {
+	indexes := make(map[string][]int)
+	for i := range o.functions {
+		if o.matched[i] {
+			continue
+		}
+
+		k := key(o, i)
+		if k != "" {
+			indexes[k] = append(indexes[k], i)
+		}
+	}
+
+	return indexes
+}
+
+// matchUnique matches unmatched functions that have the same key,
+// as long as only one unmatched function on each side has the key.
+func matchUnique(a *diffSide, b *diffSide, match FunctionMatch, key func(*diffSide, int) string) []diffPair {
+	indexesA := a.unmatchedByKey(key)
+	indexesB := b.unmatchedByKey(key)
+
+	var pairs []diffPair
+	for i := range a.functions {
+		if a.matched[i] {
+			continue
+		}
+
+		k := key(a, i)
+		if k == "" || len(indexesA[k]) != 1 || len(indexesB[k]) != 1 {
+			continue
+		}
+
+		j := indexesB[k][0]
+		a.matched[i] = true
+		b.matched[j] = true
+		pairs = append(pairs, diffPair{
+			a:     i,
+			b:     j,
+			match: match,
+		})
+	}
+
+	return pairs
+}
+
+// rawDiffInstruction is an instruction as reported by 'pdfj' or 'pdbj'.
+type rawDiffInstruction struct {
+	Opcode string  `json:"opcode"`
+	Disasm string  `json:"disasm"`
+	Jump   *uint64 `json:"jump"`
+	Fail   *uint64 `json:"fail"`
+	Ptr    *uint64 `json:"ptr"`
+	Refs   []struct {
+		Addr uint64 `json:"addr"`
+	} `json:"refs"`
+}
+
+// normalize returns the instruction with its addresses replaced by
+// symbolic values. Addresses within the function are replaced by their
+// offset from the start of the function, while other addresses are
+// replaced by "<addr>". Names that radare2 generates from addresses,
+// such as "fcn.00401000", have their address removed.
+func (o rawDiffInstruction) normalize(function Function) string {
+	text := o.Disasm
+	if text == "" {
+		text = o.Opcode
+	}
+
+	addrs := make(map[uint64]bool)
+	for _, addr := range []*uint64{o.Jump, o.Fail, o.Ptr} {
+		if addr != nil {
+			addrs[*addr] = true
+		}
+	}
+	for _, ref := range o.Refs {
+		addrs[ref.Addr] = true
+	}
+
+	text = diffHexRegex.ReplaceAllStringFunc(text, func(str string) string {
+		addr, err := strconv.ParseUint(str[2:], 16, 64)
+		if err != nil || !addrs[addr] {
+			return str
+		}
+
+		if addr >= function.Addr && addr < function.Addr+function.Size {
+			return fmt.Sprintf("<+0x%x>", addr-function.Addr)
+		}
+
+		return "<addr>"
+	})
+
+	return diffAutoNameRegex.ReplaceAllString(text, "$1.*")
+}
+
+// functionHash returns the SHA-256 hash of the function's normalized
+// instructions ('pdfj').
+func functionHash(api Api, function Function) (string, error) {
+	var disassembly struct {
+		Ops []rawDiffInstruction `json:"ops"`
+	}
+	err := api.ExecuteToJson(fmt.Sprintf("pdfj @ 0x%x", function.Addr), &disassembly)
+	if err != nil {
+		return "", err
+	}
+
+	hash := sha256.New()
+	for _, instruction := range disassembly.Ops {
+		hash.Write([]byte(instruction.normalize(function)))
+		hash.Write([]byte{'\n'})
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+type rawFunctionBasicBlock struct {
+	Addr uint64 `json:"addr"`
+	Size uint64 `json:"size"`
+}
+
+// functionBasicBlocks returns the basic blocks of the function
+// ('afbj'), sorted by address.
+func functionBasicBlocks(api Api, function Function) ([]*DiffBasicBlock, error) {
+	var raws []rawFunctionBasicBlock
+	err := api.ExecuteToJson(fmt.Sprintf("afbj @ 0x%x", function.Addr), &raws)
+	if err != nil {
+		return nil, err
+	}
+
+	blocks := make([]*DiffBasicBlock, len(raws))
+	for i, raw := range raws {
+		var instructions []rawDiffInstruction
+		err := api.ExecuteToJson(fmt.Sprintf("pdbj @ 0x%x", raw.Addr), &instructions)
+		if err != nil {
+			return nil, err
+		}
+
+		block := &DiffBasicBlock{
+			Addr:         raw.Addr,
+			Size:         raw.Size,
+			Instructions: make([]string, len(instructions)),
+		}
+
+		keys := make([]string, len(instructions))
+		for j, instruction := range instructions {
+			block.Instructions[j] = instruction.Opcode
+			if block.Instructions[j] == "" {
+				block.Instructions[j] = instruction.Disasm
+			}
+
+			keys[j] = instruction.normalize(function)
+		}
+		block.key = strings.Join(keys, "\n")
+
+		pdb, err := api.Execute(fmt.Sprintf("pdb @ 0x%x", raw.Addr))
+		if err != nil {
+			return nil, err
+		}
+
+		block.text, err = PdbToBasicBlockText(strings.NewReader(pdb))
+		if err != nil {
+			return nil, err
+		}
+
+		blocks[i] = block
+	}
+
+	sort.Slice(blocks, func(i int, j int) bool {
+		return blocks[i].Addr < blocks[j].Addr
+	})
+
+	return blocks, nil
+}
+
+// diffBasicBlocks compares the basic blocks of two functions. Blocks
+// with the same normalized instructions are unchanged. The remaining
+// blocks are paired in address order and are considered changed.
+func diffBasicBlocks(a Api, functionA Function, b Api, functionB Function) ([]BasicBlockDiff, error) {
+	blocksA, err := functionBasicBlocks(a, functionA)
+	if err != nil {
+		return nil, err
+	}
+
+	blocksB, err := functionBasicBlocks(b, functionB)
+	if err != nil {
+		return nil, err
+	}
+
+	// Blocks are indexed by their normalized instructions. Blocks
+	// with the same instructions are paired in address order.
+	unmatchedB := make(map[string][]*DiffBasicBlock)
+	for _, blockB := range blocksB {
+		unmatchedB[blockB.key] = append(unmatchedB[blockB.key], blockB)
+	}
+
+	var diffs []BasicBlockDiff
+	var remainingA []*DiffBasicBlock
+	matchedB := make(map[*DiffBasicBlock]bool)
+
+	for _, blockA := range blocksA {
+		candidates := unmatchedB[blockA.key]
+		if len(candidates) == 0 {
+			remainingA = append(remainingA, blockA)
+			continue
+		}
+
+		blockB := candidates[0]
+		unmatchedB[blockA.key] = candidates[1:]
+		matchedB[blockB] = true
+		diffs = append(diffs, BasicBlockDiff{
+			Status: DiffUnchanged,
+			A:      blockA,
+			B:      blockB,
+		})
+	}
+
+	var remainingB []*DiffBasicBlock
+	for _, blockB := range blocksB {
+		if !matchedB[blockB] {
+			remainingB = append(remainingB, blockB)
+		}
+	}
+
+	for i := 0; i < len(remainingA) || i < len(remainingB); i++ {
+		switch {
+		case i < len(remainingA) && i < len(remainingB):
+			diffs = append(diffs, BasicBlockDiff{
+				Status: DiffChanged,
+				A:      remainingA[i],
+				B:      remainingB[i],
+			})
+		case i < len(remainingA):
+			diffs = append(diffs, BasicBlockDiff{
+				Status: DiffRemoved,
+				A:      remainingA[i],
+			})
+		default:
+			diffs = append(diffs, BasicBlockDiff{
+				Status: DiffAdded,
+				B:      remainingB[i],
+			})
+		}
+	}
+
+	sort.SliceStable(diffs, func(i int, j int) bool {
+		return basicBlockDiffAddr(diffs[i]) < basicBlockDiffAddr(diffs[j])
+	})
+
+	return diffs, nil
+}
+
+func basicBlockDiffAddr(diff BasicBlockDiff) uint64 {
+	if diff.A != nil {
+		return diff.A.Addr
+	}
+
+	return diff.B.Addr
+}
@@ -0,0 +1,228 @@
+package radareutil
+
+import (
+	"reflect"
+	"testing"
+)
+
+// diffTestApis returns two sessions of the same program. In the second,
+// the program was relinked at a different address, the function "helper"
+// returns a different value, "gone" was removed, and "new" was added.
+func diffTestApis() (*scriptedApi, *scriptedApi) {
+	a := &scriptedApi{
+		outputs: map[string]string{
+			"aflj": `[
+				{"offset":4096,"name":"main","size":16,"cc":1,"nbbs":1,"ninstrs":4},
+				{"offset":4128,"name":"fcn.00001020","size":6,"cc":1,"nbbs":1,"ninstrs":2},
+				{"offset":4144,"name":"helper","size":6,"cc":1,"nbbs":1,"ninstrs":2},
+				{"offset":4160,"name":"gone","size":8,"cc":1,"nbbs":1,"ninstrs":3}
+			]`,
+			"pdfj @ 0x1000": `{"ops":[
+				{"opcode":"push rbp","disasm":"push rbp"},
+				{"opcode":"call 0x1100","disasm":"call sym.imp.puts","jump":4352},
+				{"opcode":"jmp 0x1008","disasm":"jmp 0x1008","jump":4104},
+				{"opcode":"ret","disasm":"ret"}
+			]}`,
+			"pdfj @ 0x1020": `{"ops":[
+				{"opcode":"call 0x1000","disasm":"call main","jump":4096},
+				{"opcode":"ret","disasm":"ret"}
+			]}`,
+			"pdfj @ 0x1030": `{"ops":[
+				{"opcode":"mov eax, 3","disasm":"mov eax, 3"},
+				{"opcode":"ret","disasm":"ret"}
+			]}`,
+			"pdfj @ 0x1040": `{"ops":[
+				{"opcode":"mov eax, 2","disasm":"mov eax, 2"},
+				{"opcode":"nop","disasm":"nop"},
+				{"opcode":"ret","disasm":"ret"}
+			]}`,
+			"afbj @ 0x1030": `[{"addr":4144,"size":6}]`,
+			"pdbj @ 0x1030": `[{"opcode":"mov eax, 3","disasm":"mov eax, 3"},{"opcode":"ret","disasm":"ret"}]`,
+			"pdb @ 0x1030":  "            b803000000     mov eax, 3\n            c3             ret\n",
+		},
+	}
+
+	b := &scriptedApi{
+		outputs: map[string]string{
+			"aflj": `[
+				{"offset":8192,"name":"main","size":16,"cc":1,"nbbs":1,"ninstrs":4},
+				{"offset":8224,"name":"fcn.00002020","size":6,"cc":1,"nbbs":1,"ninstrs":2},
+				{"offset":8240,"name":"helper","size":6,"cc":1,"nbbs":1,"ninstrs":2},
+				{"offset":8256,"name":"new","size":3,"cc":1,"nbbs":1,"ninstrs":2}
+			]`,
+			"pdfj @ 0x2000": `{"ops":[
+				{"opcode":"push rbp","disasm":"push rbp"},
+				{"opcode":"call 0x2200","disasm":"call sym.imp.puts","jump":8704},
+				{"opcode":"jmp 0x2008","disasm":"jmp 0x2008","jump":8200},
+				{"opcode":"ret","disasm":"ret"}
+			]}`,
+			"pdfj @ 0x2020": `{"ops":[
+				{"opcode":"call 0x2000","disasm":"call main","jump":8192},
+				{"opcode":"ret","disasm":"ret"}
+			]}`,
+			"pdfj @ 0x2030": `{"ops":[
+				{"opcode":"mov eax, 4","disasm":"mov eax, 4"},
+				{"opcode":"ret","disasm":"ret"}
+			]}`,
+			"pdfj @ 0x2040": `{"ops":[
+				{"opcode":"xor eax, eax","disasm":"xor eax, eax"},
+				{"opcode":"ret","disasm":"ret"}
+			]}`,
+			"afbj @ 0x2030": `[{"addr":8240,"size":6}]`,
+			"pdbj @ 0x2030": `[{"opcode":"mov eax, 4","disasm":"mov eax, 4"},{"opcode":"ret","disasm":"ret"}]`,
+			"pdb @ 0x2030":  "            b804000000     mov eax, 4\n            c3             ret\n",
+		},
+	}
+
+	return a, b
+}
+
+func TestDiffBinariesRelinked(t *testing.T) {
+	a, b := diffTestApis()
+
+	diff, err := DiffBinaries(a, b, &DiffOptions{
+		IncludeUnchanged: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type summary struct {
+		name   string
+		status DiffStatus
+		match  FunctionMatch
+	}
+
+	var summaries []summary
+	for _, function := range diff.Functions {
+		summaries = append(summaries, summary{
+			name:   function.Name(),
+			status: function.Status,
+			match:  function.Match,
+		})
+	}
+
+	expected := []summary{
+		{name: "fcn.00002020", status: DiffUnchanged, match: MatchHash},
+		{name: "gone", status: DiffRemoved},
+		{name: "helper", status: DiffChanged, match: MatchName},
+		{name: "main", status: DiffUnchanged, match: MatchName},
+		{name: "new", status: DiffAdded},
+	}
+	if !reflect.DeepEqual(summaries, expected) {
+		t.Fatalf("diff is %+v - expected %+v", summaries, expected)
+	}
+
+	helper := diff.Functions[2]
+	if len(helper.BasicBlocks) != 1 || helper.BasicBlocks[0].Status != DiffChanged {
+		t.Fatalf("unexpected basic block diff %+v", helper.BasicBlocks)
+	}
+
+	instructions := helper.BasicBlocks[0].B.Instructions
+	if !reflect.DeepEqual(instructions, []string{"mov eax, 4", "ret"}) {
+		t.Errorf("basic block instructions are %q", instructions)
+	}
+}
+
+func TestDiffBinariesSignature(t *testing.T) {
+	a := &scriptedApi{
+		outputs: map[string]string{
+			"aflj": `[
+				{"offset":4096,"name":"parse_header","size":8,"cc":1,"nbbs":1,"ninstrs":2,"signature":"int parse_header (char *buf);"},
+				{"offset":4112,"name":"entry0","size":4,"cc":1,"nbbs":1,"ninstrs":2,"signature":"entry0 ();"}
+			]`,
+			"pdfj @ 0x1000": `{"ops":[{"opcode":"mov eax, 1","disasm":"mov eax, 1"},{"opcode":"ret","disasm":"ret"}]}`,
+			"pdfj @ 0x1010": `{"ops":[{"opcode":"nop","disasm":"nop"},{"opcode":"ret","disasm":"ret"}]}`,
+			"afbj @ 0x1000": `[{"addr":4096,"size":8}]`,
+			"pdbj @ 0x1000": `[{"opcode":"mov eax, 1","disasm":"mov eax, 1"},{"opcode":"ret","disasm":"ret"}]`,
+			"pdb @ 0x1000":  "            b801000000     mov eax, 1\n            c3             ret\n",
+		},
+	}
+
+	b := &scriptedApi{
+		outputs: map[string]string{
+			"aflj": `[
+				{"offset":8192,"name":"parse_hdr","size":10,"cc":1,"nbbs":1,"ninstrs":3,"signature":"int parse_hdr (char *buf);"},
+				{"offset":8208,"name":"entry0","size":6,"cc":1,"nbbs":1,"ninstrs":3,"signature":"void entry0 (int x);"}
+			]`,
+			"pdfj @ 0x2000": `{"ops":[{"opcode":"xor eax, eax","disasm":"xor eax, eax"},{"opcode":"inc eax","disasm":"inc eax"},{"opcode":"ret","disasm":"ret"}]}`,
+			"pdfj @ 0x2010": `{"ops":[{"opcode":"nop","disasm":"nop"},{"opcode":"nop","disasm":"nop"},{"opcode":"ret","disasm":"ret"}]}`,
+			"afbj @ 0x2000": `[{"addr":8192,"size":10}]`,
+			"pdbj @ 0x2000": `[{"opcode":"xor eax, eax","disasm":"xor eax, eax"},{"opcode":"inc eax","disasm":"inc eax"},{"opcode":"ret","disasm":"ret"}]`,
+			"pdb @ 0x2000":  "            31c0           xor eax, eax\n            ffc0           inc eax\n            c3             ret\n",
+		},
+	}
+
+	diff, err := DiffBinaries(a, b, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type summary struct {
+		name   string
+		status DiffStatus
+		match  FunctionMatch
+	}
+
+	var summaries []summary
+	for _, function := range diff.Functions {
+		summaries = append(summaries, summary{
+			name:   function.Name(),
+			status: function.Status,
+			match:  function.Match,
+		})
+	}
+
+	expected := []summary{
+		{name: "entry0", status: DiffRemoved},
+		{name: "entry0", status: DiffAdded},
+		{name: "parse_hdr", status: DiffChanged, match: MatchSignature},
+	}
+	if !reflect.DeepEqual(summaries, expected) {
+		t.Fatalf("diff is %+v - expected %+v", summaries, expected)
+	}
+}
+
+func TestNormalizeDiffInstruction(t *testing.T) {
+	function := Function{
+		Addr: 0x1000,
+		Size: 0x20,
+	}
+
+	jump := uint64(0x1010)
+	ptr := uint64(0x4028)
+	call := uint64(0x1200)
+
+	tests := []struct {
+		instruction rawDiffInstruction
+		expected    string
+	}{
+		{
+			instruction: rawDiffInstruction{Disasm: "jne 0x1010", Jump: &jump},
+			expected:    "jne <+0x10>",
+		},
+		{
+			instruction: rawDiffInstruction{Disasm: "mov eax, dword [0x4028]", Ptr: &ptr},
+			expected:    "mov eax, dword [<addr>]",
+		},
+		{
+			instruction: rawDiffInstruction{Disasm: "call fcn.00001200", Jump: &call},
+			expected:    "call fcn.*",
+		},
+		{
+			instruction: rawDiffInstruction{Disasm: "mov eax, 0x1010"},
+			expected:    "mov eax, 0x1010",
+		},
+		{
+			instruction: rawDiffInstruction{Opcode: "ret"},
+			expected:    "ret",
+		},
+	}
+
+	for _, test := range tests {
+		result := test.instruction.normalize(function)
+		if result != test.expected {
+			t.Errorf("normalized '%s' to '%s' - expected '%s'", test.instruction.Disasm, result, test.expected)
+		}
+	}
+}
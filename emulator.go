@@ -0,0 +1,230 @@
+package radareutil
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	// DefaultEmulatorMaxSteps is the default value of Emulator.MaxSteps.
+	DefaultEmulatorMaxSteps = 1000000
+)
+
+// EmulationStep is an instruction executed by an Emulator while tracing.
+type EmulationStep struct {
+	// Pc is the address of the instruction.
+	Pc uint64
+
+	// MemoryWrites are the addresses the instruction wrote to.
+	MemoryWrites []uint64
+}
+
+// Emulator partially emulates code using radare2's ESIL VM. It does not
+// require a debugger, which makes it useful for running small pieces of
+// code such as string decryption routines.
+type Emulator struct {
+	api     Api
+	pcName  string
+	tracing bool
+	trace   []EmulationStep
+
+	// MaxSteps limits the number of instructions StepUntil and
+	// ContinueUntil execute while tracing.
+	MaxSteps int
+}
+
+// Init initializes the ESIL VM state ('aei') and stack memory ('aeim'),
+// and sets the program counter to addr.
+func (o *Emulator) Init(addr uint64) error {
+	for _, command := range []string{"aei", "aeim", fmt.Sprintf("aepc 0x%x", addr)} {
+		_, err := o.api.Execute(command)
+		if err != nil {
+			return fmt.Errorf("failed to execute '%s' - %s", command, err.Error())
+		}
+	}
+
+	return nil
+}
+
+// Registers returns the values of the emulated registers ('aerj').
+func (o *Emulator) Registers() (map[string]uint64, error) {
+	var registers map[string]uint64
+	err := o.api.ExecuteToJson("aerj", &registers)
+	if err != nil {
+		return nil, err
+	}
+
+	return registers, nil
+}
+
+// Register returns the value of the named emulated register.
+func (o *Emulator) Register(name string) (uint64, error) {
+	registers, err := o.Registers()
+	if err != nil {
+		return 0, err
+	}
+
+	value, exists := registers[name]
+	if !exists {
+		return 0, fmt.Errorf("unknown register '%s'", name)
+	}
+
+	return value, nil
+}
+
+// SetRegister sets the value of the named emulated register ('aer').
+func (o *Emulator) SetRegister(name string, value uint64) error {
+	err := validateRegisterName(name)
+	if err != nil {
+		return err
+	}
+
+	_, err = o.api.Execute(fmt.Sprintf("aer %s=0x%x", name, value))
+	return err
+}
+
+// Pc returns the value of the emulated program counter.
+func (o *Emulator) Pc() (uint64, error) {
+	if o.pcName == "" {
+		name, err := o.api.Execute("drn PC")
+		if err != nil {
+			return 0, err
+		}
+
+		o.pcName = strings.TrimSpace(name)
+		if o.pcName == "" {
+			return 0, errors.New("failed to get name of the program counter register")
+		}
+	}
+
+	return o.Register(o.pcName)
+}
+
+// ReadMemory reads size bytes of emulated memory at addr ('p8').
+func (o *Emulator) ReadMemory(addr uint64, size int) ([]byte, error) {
+	return readMemory(o.api, addr, size)
+}
+
+// WriteMemory writes data to emulated memory at addr ('wx'). Unless
+// radare2 was started in write mode, the 'io.cache' eval variable must
+// be enabled for writes outside of the ESIL stack.
+func (o *Emulator) WriteMemory(addr uint64, data []byte) error {
+	return writeMemory(o.api, addr, data)
+}
+
+// Step executes a single instruction ('aes').
+func (o *Emulator) Step() error {
+	if o.tracing {
+		err := o.recordStep()
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err := o.api.Execute("aes")
+	return err
+}
+
+// StepUntil executes instructions until the program counter is
+// addr ('aesu').
+func (o *Emulator) StepUntil(addr uint64) error {
+	if o.tracing {
+		return o.stepUntil(addr)
+	}
+
+	_, err := o.api.Execute(fmt.Sprintf("aesu 0x%x", addr))
+	return err
+}
+
+// ContinueUntil continues emulation until the program counter is
+// addr ('aecu').
+func (o *Emulator) ContinueUntil(addr uint64) error {
+	if o.tracing {
+		return o.stepUntil(addr)
+	}
+
+	_, err := o.api.Execute(fmt.Sprintf("aecu 0x%x", addr))
+	return err
+}
+
+// stepUntil steps one instruction at a time so that each step is traced.
+func (o *Emulator) stepUntil(addr uint64) error {
+	maxSteps := o.MaxSteps
+	if maxSteps <= 0 {
+		maxSteps = DefaultEmulatorMaxSteps
+	}
+
+	for i := 0; i < maxSteps; i++ {
+		pc, err := o.Pc()
+		if err != nil {
+			return err
+		}
+
+		if pc == addr {
+			return nil
+		}
+
+		err = o.Step()
+		if err != nil {
+			return err
+		}
+	}
+
+	return fmt.Errorf("did not reach 0x%x after %d steps", addr, maxSteps)
+}
+
+type esilAccesses struct {
+	MemoryWrites []uint64 `json:"@W"`
+}
+
+// recordStep adds the instruction that is about to be executed to the trace.
+func (o *Emulator) recordStep() error {
+	pc, err := o.Pc()
+	if err != nil {
+		return err
+	}
+
+	var accesses esilAccesses
+	err = o.api.ExecuteToJson(fmt.Sprintf("aeaj 1 @ 0x%x", pc), &accesses)
+	if err != nil {
+		return fmt.Errorf("failed to get memory accesses at 0x%x - %s", pc, err.Error())
+	}
+
+	o.trace = append(o.trace, EmulationStep{
+		Pc:           pc,
+		MemoryWrites: accesses.MemoryWrites,
+	})
+
+	return nil
+}
+
+// StartTrace starts recording the instructions that are executed and
+// the memory they write to. Tracing requires additional commands to be
+// executed for each instruction, which makes emulation slower.
+func (o *Emulator) StartTrace() {
+	o.tracing = true
+}
+
+// StopTrace stops recording instructions.
+func (o *Emulator) StopTrace() {
+	o.tracing = false
+}
+
+// Trace returns the instructions that were recorded while tracing.
+func (o *Emulator) Trace() []EmulationStep {
+	return o.trace
+}
+
+// ClearTrace discards the recorded instructions.
+func (o *Emulator) ClearTrace() {
+	o.trace = nil
+}
+
+// NewEmulator returns an Emulator for the provided Api.
+func NewEmulator(api Api) *Emulator {
+	return &Emulator{
+		api:      api,
+		MaxSteps: DefaultEmulatorMaxSteps,
+	}
+}
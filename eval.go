@@ -0,0 +1,145 @@
+package radareutil
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Eval gets and sets radare2's eval (configuration) variables.
+type Eval struct {
+	api Api
+}
+
+// Get returns the value of the named eval variable ('e name').
+func (o *Eval) Get(name string) (string, error) {
+	value, err := o.api.Execute(quoteCommand("e " + name))
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimRight(value, "\n"), nil
+}
+
+// GetBool returns the value of the named boolean eval variable.
+func (o *Eval) GetBool(name string) (bool, error) {
+	value, err := o.Get(name)
+	if err != nil {
+		return false, err
+	}
+
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("eval variable '%s' is not a boolean - %s", name, err.Error())
+	}
+
+	return b, nil
+}
+
+// GetInt returns the value of the named numeric eval variable.
+// Hexadecimal values prefixed with "0x" are supported.
+func (o *Eval) GetInt(name string) (int64, error) {
+	value, err := o.Get(name)
+	if err != nil {
+		return 0, err
+	}
+
+	i, err := strconv.ParseInt(value, 0, 64)
+	if err != nil {
+		return 0, fmt.Errorf("eval variable '%s' is not a number - %s", name, err.Error())
+	}
+
+	return i, nil
+}
+
+// Set sets the value of the named eval variable ('e name=value').
+func (o *Eval) Set(name string, value string) error {
+	_, err := o.api.Execute(quoteCommand("e " + name + "=" + value))
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// List returns all eval variables and their values ('ej').
+func (o *Eval) List() (map[string]string, error) {
+	var raws map[string]json.RawMessage
+	err := o.api.ExecuteToJson("ej", &raws)
+	if err != nil {
+		return nil, err
+	}
+
+	vars := make(map[string]string, len(raws))
+	for name, raw := range raws {
+		var str string
+		if json.Unmarshal(raw, &str) == nil {
+			vars[name] = str
+		} else {
+			vars[name] = string(raw)
+		}
+	}
+
+	return vars, nil
+}
+
+// NewEval returns an Eval for the provided Api.
+func NewEval(api Api) *Eval {
+	return &Eval{
+		api: api,
+	}
+}
+
+// WithEval sets the specified eval variables, calls fn, and then restores
+// the variables to their original values. The original values are read
+// using 'ej' before any variable is changed. The variables are restored
+// even if fn returns an error or panics. If fn succeeds but the variables
+// cannot be restored, the restore error is returned.
+func WithEval(api Api, overrides map[string]string, fn func() error) (err error) {
+	eval := NewEval(api)
+
+	current, err := eval.List()
+	if err != nil {
+		return fmt.Errorf("failed to get current eval variables - %s", err.Error())
+	}
+
+	names := make([]string, 0, len(overrides))
+	for name := range overrides {
+		if _, exists := current[name]; !exists {
+			return fmt.Errorf("unknown eval variable '%s'", name)
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var changed []string
+
+	defer func() {
+		var failed []string
+		for _, name := range changed {
+			restoreErr := eval.Set(name, current[name])
+			if restoreErr != nil {
+				failed = append(failed, name+" ("+restoreErr.Error()+")")
+			}
+		}
+
+		if err == nil && len(failed) > 0 {
+			err = fmt.Errorf("failed to restore eval variables - %s", strings.Join(failed, ", "))
+		}
+	}()
+
+	for _, name := range names {
+		// Record the variable before setting it in case
+		// radare2 applied the change but returned an error.
+		changed = append(changed, name)
+
+		err = eval.Set(name, overrides[name])
+		if err != nil {
+			return fmt.Errorf("failed to set eval variable '%s' - %s", name, err.Error())
+		}
+	}
+
+	return fn()
+}
@@ -0,0 +1,27 @@
+package radareutil
+
+// Function is a function that radare2 has analyzed, as reported by 'aflj'.
+type Function struct {
+	Addr         uint64 `json:"offset"`
+	Name         string `json:"name"`
+	Size         uint64 `json:"size"`
+	RealSize     uint64 `json:"realsz"`
+	Complexity   int    `json:"cc"`
+	BasicBlocks  int    `json:"nbbs"`
+	Instructions int    `json:"ninstrs"`
+	Edges        int    `json:"edges"`
+	CallType     string `json:"calltype"`
+	Signature    string `json:"signature"`
+}
+
+// Functions returns the functions that radare2 has analyzed. Functions
+// must be analyzed beforehand, for example by executing 'aaa'.
+func Functions(api Api) ([]Function, error) {
+	var functions []Function
+	err := api.ExecuteToJson("aflj", &functions)
+	if err != nil {
+		return nil, err
+	}
+
+	return functions, nil
+}
@@ -0,0 +1,226 @@
+package radareutil
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const (
+	HashMd5     = "md5"
+	HashSha1    = "sha1"
+	HashSha256  = "sha256"
+	HashSha512  = "sha512"
+	HashCrc32   = "crc32"
+	HashSsdeep  = "ssdeep"
+	HashEntropy = "entropy"
+)
+
+// BlockHash contains the hashes of a block of data.
+type BlockHash struct {
+	Addr uint64
+	Size uint64
+
+	// Hashes maps each requested hash to its value.
+	Hashes map[string]string
+
+	// Entropy is the block's entropy, from 0 to 8. It is only set if
+	// HashEntropy was requested.
+	Entropy float64
+}
+
+// EntropyBlock is a block in an EntropyHistogram.
+type EntropyBlock struct {
+	Addr uint64
+
+	// Entropy is the block's entropy, from 0 to 8. Blocks smaller
+	// than 256 bytes have an entropy of at most log2(block size).
+	Entropy float64
+}
+
+// EntropyHistogram is the entropy of consecutive blocks of data, as
+// reported by 'p=ej'.
+type EntropyHistogram struct {
+	Addr      uint64
+	Size      uint64
+	BlockSize uint64
+	Blocks    []EntropyBlock
+}
+
+// SectionEntropy is the entropy histogram of a section.
+type SectionEntropy struct {
+	Section   Section
+	Histogram *EntropyHistogram
+}
+
+type rawEntropyHistogram struct {
+	BlockSize uint64 `json:"blocksize"`
+	Address   uint64 `json:"address"`
+	Size      uint64 `json:"size"`
+	Entropy   []struct {
+		Addr  uint64  `json:"addr"`
+		Value float64 `json:"value"`
+	} `json:"entropy"`
+}
+
+// Hash calculates a hash, such as HashSha256, of size bytes at
+// addr ('ph'). Refer to 'ph?' for the list of supported hashes.
+func Hash(api Api, algorithm string, addr uint64, size uint64) (string, error) {
+	err := validateAnnotationName(algorithm)
+	if err != nil {
+		return "", fmt.Errorf("invalid hash algorithm - %s", err.Error())
+	}
+
+	if size == 0 {
+		return "", errors.New("hash size is zero")
+	}
+
+	output, err := api.Execute(fmt.Sprintf("ph %s %d @ 0x%x", algorithm, size, addr))
+	if err != nil {
+		return "", err
+	}
+
+	hash := strings.TrimSpace(output)
+	if hash == "" {
+		return "", fmt.Errorf("radare2 did not produce a '%s' hash", algorithm)
+	}
+
+	return hash, nil
+}
+
+// Entropy calculates the entropy of size bytes at addr, from 0 to 8
+// ('ph entropy').
+func Entropy(api Api, addr uint64, size uint64) (float64, error) {
+	output, err := Hash(api, HashEntropy, addr, size)
+	if err != nil {
+		return 0, err
+	}
+
+	entropy, err := strconv.ParseFloat(output, 64)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse entropy - %s", err.Error())
+	}
+
+	return entropy, nil
+}
+
+// BlockHashes splits size bytes at addr into blocks of blockSize bytes
+// and calculates the requested hashes of each block. The last block is
+// smaller than blockSize if size is not a multiple of it.
+func BlockHashes(api Api, addr uint64, size uint64, blockSize uint64, algorithms []string) ([]BlockHash, error) {
+	if blockSize == 0 {
+		return nil, errors.New("block size is zero")
+	}
+
+	if len(algorithms) == 0 {
+		return nil, errors.New("no hash algorithms were specified")
+	}
+
+	var blocks []BlockHash
+	for offset := uint64(0); offset < size; offset += blockSize {
+		block := BlockHash{
+			Addr:   addr + offset,
+			Size:   blockSize,
+			Hashes: make(map[string]string),
+		}
+
+		if remaining := size - offset; remaining < blockSize {
+			block.Size = remaining
+		}
+
+		for _, algorithm := range algorithms {
+			hash, err := Hash(api, algorithm, block.Addr, block.Size)
+			if err != nil {
+				return nil, fmt.Errorf("failed to calculate %s of block at 0x%x - %s",
+					algorithm, block.Addr, err.Error())
+			}
+
+			block.Hashes[algorithm] = hash
+
+			if algorithm == HashEntropy {
+				block.Entropy, err = strconv.ParseFloat(hash, 64)
+				if err != nil {
+					return nil, fmt.Errorf("failed to parse entropy of block at 0x%x - %s",
+						block.Addr, err.Error())
+				}
+			}
+		}
+
+		blocks = append(blocks, block)
+	}
+
+	return blocks, nil
+}
+
+// Entropies returns the entropy histogram of size bytes at addr split
+// into the specified number of blocks ('p=ej').
+func Entropies(api Api, addr uint64, size uint64, blocks int) (*EntropyHistogram, error) {
+	if size == 0 {
+		return nil, errors.New("entropy size is zero")
+	}
+
+	if blocks <= 0 {
+		return nil, errors.New("number of entropy blocks must be greater than zero")
+	}
+
+	var raw rawEntropyHistogram
+	err := api.ExecuteToJson(fmt.Sprintf("p=ej %d %d @ 0x%x", blocks, size, addr), &raw)
+	if err != nil {
+		return nil, err
+	}
+
+	histogram := &EntropyHistogram{
+		Addr:      raw.Address,
+		Size:      raw.Size,
+		BlockSize: raw.BlockSize,
+		Blocks:    make([]EntropyBlock, len(raw.Entropy)),
+	}
+
+	// radare2 reports the fraction of the block's maximum possible
+	// entropy, log2(min(block size, 256)), scaled to 0-255.
+	var maxEntropy float64
+	if raw.BlockSize > 1 {
+		maxEntropy = math.Log2(math.Min(float64(raw.BlockSize), 256))
+	}
+
+	for i, block := range raw.Entropy {
+		histogram.Blocks[i] = EntropyBlock{
+			Addr:    block.Addr,
+			Entropy: block.Value * maxEntropy / 255,
+		}
+	}
+
+	return histogram, nil
+}
+
+// SectionEntropies returns the entropy histogram of each section
+// split into the specified number of blocks, in the order the sections
+// are reported by Sections. Sections without data in the file, such as
+// .bss, are skipped.
+func SectionEntropies(api Api, blocks int) ([]SectionEntropy, error) {
+	sections, err := Sections(api, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var entropies []SectionEntropy
+	for _, section := range sections {
+		if section.Size == 0 {
+			continue
+		}
+
+		histogram, err := Entropies(api, section.Vaddr, section.Size, blocks)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get entropy of section '%s' - %s", section.Name, err.Error())
+		}
+
+		entropies = append(entropies, SectionEntropy{
+			Section:   section,
+			Histogram: histogram,
+		})
+	}
+
+	return entropies, nil
+}
@@ -0,0 +1,82 @@
+package radareutil
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSectionEntropiesDuplicateNames(t *testing.T) {
+	api := &scriptedApi{
+		outputs: map[string]string{
+			"iSj": `[
+				{"name":"","size":16,"vsize":16,"perm":"-r--","paddr":0,"vaddr":4096},
+				{"name":"","size":32,"vsize":32,"perm":"-r-x","paddr":16,"vaddr":8192},
+				{"name":".bss","size":0,"vsize":64,"perm":"-rw-","paddr":0,"vaddr":12288}
+			]`,
+			"p=ej 1 16 @ 0x1000": `{"blocksize":16,"address":4096,"size":16,"entropy":[{"addr":4096,"value":255}]}`,
+			"p=ej 1 32 @ 0x2000": `{"blocksize":32,"address":8192,"size":32,"entropy":[{"addr":8192,"value":0}]}`,
+		},
+	}
+
+	entropies, err := SectionEntropies(api, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(entropies) != 2 {
+		t.Fatalf("got %d section entropies - expected 2", len(entropies))
+	}
+
+	for i, expected := range []struct {
+		vaddr   uint64
+		entropy float64
+	}{
+		{vaddr: 0x1000, entropy: 4},
+		{vaddr: 0x2000, entropy: 0},
+	} {
+		entropy := entropies[i]
+		if entropy.Section.Vaddr != expected.vaddr {
+			t.Errorf("section %d is at 0x%x - expected 0x%x", i, entropy.Section.Vaddr, expected.vaddr)
+		}
+
+		if len(entropy.Histogram.Blocks) != 1 || entropy.Histogram.Blocks[0].Entropy != expected.entropy {
+			t.Errorf("section %d has entropy blocks %+v - expected %f", i, entropy.Histogram.Blocks, expected.entropy)
+		}
+	}
+}
+
+func TestEntropies(t *testing.T) {
+	api := &scriptedApi{
+		outputs: map[string]string{
+			"p=ej 4 16 @ 0x1000":   `{"blocksize":4,"address":4096,"size":16,"entropy":[{"addr":4096,"value":255},{"addr":4100,"value":127.5},{"addr":4104,"value":0},{"addr":4108,"value":255}]}`,
+			"p=ej 2 1024 @ 0x2000": `{"blocksize":512,"address":8192,"size":1024,"entropy":[{"addr":8192,"value":255},{"addr":8704,"value":51}]}`,
+		},
+	}
+
+	tests := []struct {
+		blocks   int
+		size     uint64
+		addr     uint64
+		expected []float64
+	}{
+		{blocks: 4, size: 16, addr: 0x1000, expected: []float64{2, 1, 0, 2}},
+		{blocks: 2, size: 1024, addr: 0x2000, expected: []float64{8, 1.6}},
+	}
+
+	for _, test := range tests {
+		histogram, err := Entropies(api, test.addr, test.size, test.blocks)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(histogram.Blocks) != len(test.expected) {
+			t.Fatalf("got %d blocks - expected %d", len(histogram.Blocks), len(test.expected))
+		}
+
+		for i, block := range histogram.Blocks {
+			if math.Abs(block.Entropy-test.expected[i]) > 0.0001 {
+				t.Errorf("block %d at 0x%x has entropy %f - expected %f", i, block.Addr, block.Entropy, test.expected[i])
+			}
+		}
+	}
+}
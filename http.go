@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"net/url"
@@ -39,6 +40,10 @@ func (o *httpServerApi) Status() Status {
 	return o.r2.status()
 }
 
+func (o *httpServerApi) debuggeeExitStatus(pid int) (int, bool) {
+	return o.r2.debuggeeExitStatus(pid)
+}
+
 func (o *httpServerApi) OnStopped() chan StoppedInfo {
 	return o.r2.onStopped()
 }
@@ -166,14 +171,24 @@ func NewCustomHttpServerApi(address *url.URL, httpClient *http.Client, config *R
 	}, nil
 }
 
-func executeHttpCall(command string, address *url.URL, httpClient *http.Client, trim bool) ([]byte, error) {
-	resp, err := httpClient.Get(address.String() + cmdSubPath + "/" + command)
+func (o *httpServerApi) ExecuteStream(command string) (io.ReadCloser, error) {
+	current := o.r2.status().State
+	if current != Running {
+		return nil, fmt.Errorf("cannot execute command - state is %s", current)
+	}
+
+	resp, err := startHttpCall(command, o.address, o.client)
 	if err != nil {
 		return nil, err
 	}
 
-	if resp.Body == nil {
-		return nil, errors.New("http response body is empty")
+	return resp.Body, nil
+}
+
+func executeHttpCall(command string, address *url.URL, httpClient *http.Client, trim bool) ([]byte, error) {
+	resp, err := startHttpCall(command, address, httpClient)
+	if err != nil {
+		return nil, err
 	}
 	defer resp.Body.Close()
 
@@ -182,19 +197,37 @@ func executeHttpCall(command string, address *url.URL, httpClient *http.Client,
 		return nil, err
 	}
 
+	if trim {
+		raw = bytes.TrimSpace(raw)
+	}
+
+	return raw, nil
+}
+
+// startHttpCall executes a command and returns the response once its
+// headers have been received. The caller must close the response body.
+func startHttpCall(command string, address *url.URL, httpClient *http.Client) (*http.Response, error) {
+	resp, err := httpClient.Get(address.String() + cmdSubPath + "/" + command)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.Body == nil {
+		return nil, errors.New("http response body is empty")
+	}
+
 	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+
 		base := "request failed with code " + strconv.Itoa(resp.StatusCode)
 
-		if len(raw) == 0 {
+		raw, err := ioutil.ReadAll(resp.Body)
+		if err != nil || len(raw) == 0 {
 			return nil, errors.New(base)
 		}
 
 		return nil, errors.New(base + " - details - " + string(raw))
 	}
 
-	if trim {
-		raw = bytes.TrimSpace(raw)
-	}
-
-	return raw, nil
+	return resp, nil
 }
@@ -0,0 +1,148 @@
+package radareutil
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// JsonArrayStream decodes the elements of a JSON array one at a time
+// as radare2 produces them. It is useful for commands such as 'aflj',
+// 'izj', 'axj', and 'pdj' whose output may be too large to hold in
+// memory. For example:
+//
+//	stream, err := radareutil.ExecuteToJsonStream(api, "izj")
+//	if err != nil {
+//		return err
+//	}
+//	defer stream.Close()
+//
+//	for stream.Next() {
+//		var str someStringType
+//		err := stream.Decode(&str)
+//		if err != nil {
+//			return err
+//		}
+//	}
+//
+//	return stream.Err()
+//
+// The underlying Api cannot execute other commands until the
+// JsonArrayStream is closed.
+type JsonArrayStream struct {
+	reader  io.ReadCloser
+	decoder *json.Decoder
+	started bool
+	pending bool
+	done    bool
+	err     error
+}
+
+// Next advances to the next element of the array. It returns false when
+// there are no more elements or an error occurred. Callers should check
+// Err after Next returns false.
+func (o *JsonArrayStream) Next() bool {
+	if o.done {
+		return false
+	}
+
+	if !o.started {
+		o.started = true
+
+		token, err := o.decoder.Token()
+		if err == io.EOF {
+			// radare2 may not produce any output when there
+			// are no results.
+			o.done = true
+			return false
+		}
+		if err != nil {
+			o.fail(err)
+			return false
+		}
+
+		if delim, isDelim := token.(json.Delim); !isDelim || delim != '[' {
+			o.fail(fmt.Errorf("expected a json array, got '%v'", token))
+			return false
+		}
+	}
+
+	if o.pending {
+		// Skip the element the caller did not decode.
+		var skip json.RawMessage
+		err := o.decoder.Decode(&skip)
+		if err != nil {
+			o.fail(err)
+			return false
+		}
+		o.pending = false
+	}
+
+	if !o.decoder.More() {
+		_, err := o.decoder.Token()
+		if err != nil {
+			o.fail(err)
+			return false
+		}
+		o.done = true
+		return false
+	}
+
+	o.pending = true
+
+	return true
+}
+
+// Decode decodes the current element into pointer. It must be called
+// at most once after each call to Next that returned true.
+func (o *JsonArrayStream) Decode(pointer interface{}) error {
+	if !o.pending {
+		return errors.New("there is no element to decode")
+	}
+
+	o.pending = false
+
+	err := o.decoder.Decode(pointer)
+	if err != nil {
+		o.fail(err)
+		return err
+	}
+
+	return nil
+}
+
+// Err returns the first error that was encountered while reading
+// the array, if any.
+func (o *JsonArrayStream) Err() error {
+	return o.err
+}
+
+// Close stops reading the array and releases the underlying stream.
+func (o *JsonArrayStream) Close() error {
+	o.done = true
+	o.pending = false
+
+	return o.reader.Close()
+}
+
+func (o *JsonArrayStream) fail(err error) {
+	o.err = err
+	o.done = true
+	o.pending = false
+}
+
+// ExecuteToJsonStream executes a command that outputs a JSON array
+// and returns a JsonArrayStream that decodes the array's elements as
+// they are produced. The caller must close the JsonArrayStream.
+func ExecuteToJsonStream(api Api, command string) (*JsonArrayStream, error) {
+	reader, err := ExecuteStream(api, command)
+	if err != nil {
+		return nil, err
+	}
+
+	return &JsonArrayStream{
+		reader:  reader,
+		decoder: json.NewDecoder(reader),
+	}, nil
+}
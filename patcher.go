@@ -0,0 +1,276 @@
+package radareutil
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Patch is a change made to a file by a Patcher.
+type Patch struct {
+	Addr uint64
+
+	// Original are the bytes at Addr before the patch was applied.
+	Original []byte
+
+	// Patched are the bytes written to Addr.
+	Patched []byte
+
+	// Comment describes the patch, such as the assembly it was
+	// created from.
+	Comment string
+}
+
+type patchJson struct {
+	Addr     uint64 `json:"addr"`
+	Original string `json:"original"`
+	Patched  string `json:"patched"`
+	Comment  string `json:"comment,omitempty"`
+}
+
+// MarshalJSON encodes the patch with its bytes as hex strings.
+func (o Patch) MarshalJSON() ([]byte, error) {
+	return json.Marshal(patchJson{
+		Addr:     o.Addr,
+		Original: hex.EncodeToString(o.Original),
+		Patched:  hex.EncodeToString(o.Patched),
+		Comment:  o.Comment,
+	})
+}
+
+// UnmarshalJSON decodes a patch encoded by MarshalJSON.
+func (o *Patch) UnmarshalJSON(data []byte) error {
+	var raw patchJson
+	err := json.Unmarshal(data, &raw)
+	if err != nil {
+		return err
+	}
+
+	original, err := hex.DecodeString(raw.Original)
+	if err != nil {
+		return fmt.Errorf("failed to decode original bytes of patch at 0x%x - %s", raw.Addr, err.Error())
+	}
+
+	patched, err := hex.DecodeString(raw.Patched)
+	if err != nil {
+		return fmt.Errorf("failed to decode patched bytes of patch at 0x%x - %s", raw.Addr, err.Error())
+	}
+
+	if len(original) != len(patched) {
+		return fmt.Errorf("patch at 0x%x has %d original bytes and %d patched bytes",
+			raw.Addr, len(original), len(patched))
+	}
+
+	*o = Patch{
+		Addr:     raw.Addr,
+		Original: original,
+		Patched:  patched,
+		Comment:  raw.Comment,
+	}
+
+	return nil
+}
+
+// DecodePatches decodes a JSON patch file written by Patcher.Encode.
+func DecodePatches(r io.Reader) ([]Patch, error) {
+	var patches []Patch
+	err := json.NewDecoder(r).Decode(&patches)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode patches - %s", err.Error())
+	}
+
+	return patches, nil
+}
+
+// Patcher modifies the file radare2 has open and records each change
+// in an undo log. radare2 must be in write mode, for example by setting
+// Radare2Config.WriteMode, or have the 'io.cache' eval variable enabled.
+type Patcher struct {
+	api     Api
+	patches []Patch
+	nop     []byte
+}
+
+// Write writes data at addr ('wx').
+func (o *Patcher) Write(addr uint64, data []byte) error {
+	return o.write(addr, data, nil, "")
+}
+
+// Assemble assembles the provided instructions at addr ('pa') and
+// writes them. Multiple instructions are separated by ';'. It returns
+// the assembled bytes.
+func (o *Patcher) Assemble(addr uint64, assembly string) ([]byte, error) {
+	data, err := o.assemble(addr, assembly)
+	if err != nil {
+		return nil, err
+	}
+
+	err = o.write(addr, data, nil, assembly)
+	if err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+// Nop replaces size bytes at addr with no-op instructions. size must
+// be a multiple of the architecture's no-op instruction size.
+func (o *Patcher) Nop(addr uint64, size int) error {
+	if size <= 0 {
+		return nil
+	}
+
+	if o.nop == nil {
+		nop, err := o.assemble(addr, "nop")
+		if err != nil {
+			return err
+		}
+
+		if len(nop) == 0 {
+			return errors.New("assembling 'nop' produced no bytes")
+		}
+
+		o.nop = nop
+	}
+
+	if size%len(o.nop) != 0 {
+		return fmt.Errorf("size %d is not a multiple of the %d byte nop instruction", size, len(o.nop))
+	}
+
+	return o.write(addr, bytes.Repeat(o.nop, size/len(o.nop)), nil, fmt.Sprintf("nop %d", size))
+}
+
+// Apply applies patches, such as those decoded by DecodePatches. Before
+// each patch is written, the current bytes are compared to the patch's
+// original bytes. Patches that were applied before an error occurred
+// remain in the undo log.
+func (o *Patcher) Apply(patches []Patch) error {
+	for _, patch := range patches {
+		if len(patch.Original) != len(patch.Patched) {
+			return fmt.Errorf("patch at 0x%x has %d original bytes and %d patched bytes",
+				patch.Addr, len(patch.Original), len(patch.Patched))
+		}
+
+		err := o.write(patch.Addr, patch.Patched, patch.Original, patch.Comment)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Revert restores the original bytes of every patch in the undo log,
+// most recent first.
+func (o *Patcher) Revert() error {
+	for len(o.patches) > 0 {
+		patch := o.patches[len(o.patches)-1]
+
+		err := writeAndVerify(o.api, patch.Addr, patch.Original)
+		if err != nil {
+			return fmt.Errorf("failed to revert patch at 0x%x - %s", patch.Addr, err.Error())
+		}
+
+		o.patches = o.patches[:len(o.patches)-1]
+	}
+
+	return nil
+}
+
+// Patches returns the undo log, oldest patch first.
+func (o *Patcher) Patches() []Patch {
+	return o.patches
+}
+
+// Encode writes the undo log to w as a JSON patch file.
+func (o *Patcher) Encode(w io.Writer) error {
+	patches := o.patches
+	if patches == nil {
+		patches = []Patch{}
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(patches)
+}
+
+func (o *Patcher) assemble(addr uint64, assembly string) ([]byte, error) {
+	if strings.ContainsAny(assembly, "\r\n") {
+		return nil, errors.New("assembly contains a new line")
+	}
+
+	output, err := o.api.Execute(fmt.Sprintf("%s @ 0x%x", quoteCommand("pa "+assembly), addr))
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := hex.DecodeString(strings.TrimSpace(output))
+	if err != nil || len(data) == 0 {
+		return nil, fmt.Errorf("failed to assemble '%s' - %s", assembly, strings.TrimSpace(output))
+	}
+
+	return data, nil
+}
+
+// write writes data at addr and adds it to the undo log. If expected
+// is non-nil, the current bytes at addr must be equal to it.
+func (o *Patcher) write(addr uint64, data []byte, expected []byte, comment string) error {
+	if len(data) == 0 {
+		return nil
+	}
+
+	original, err := readMemory(o.api, addr, len(data))
+	if err != nil {
+		return err
+	}
+
+	if expected != nil && !bytes.Equal(original, expected) {
+		return fmt.Errorf("bytes at 0x%x are %x, expected %x", addr, original, expected)
+	}
+
+	err = writeAndVerify(o.api, addr, data)
+	if err != nil {
+		return err
+	}
+
+	o.patches = append(o.patches, Patch{
+		Addr:     addr,
+		Original: original,
+		Patched:  data,
+		Comment:  comment,
+	})
+
+	return nil
+}
+
+// writeAndVerify writes data at addr and reads it back, since radare2
+// does not report an error if a write fails.
+func writeAndVerify(api Api, addr uint64, data []byte) error {
+	err := writeMemory(api, addr, data)
+	if err != nil {
+		return err
+	}
+
+	written, err := readMemory(api, addr, len(data))
+	if err != nil {
+		return err
+	}
+
+	if !bytes.Equal(written, data) {
+		return fmt.Errorf("failed to write to 0x%x - radare2 may not be in write mode", addr)
+	}
+
+	return nil
+}
+
+// NewPatcher returns a Patcher for the provided Api.
+func NewPatcher(api Api) *Patcher {
+	return &Patcher{
+		api: api,
+	}
+}
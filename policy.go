@@ -0,0 +1,460 @@
+package radareutil
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// PolicyError is returned when a command is rejected by a Policy.
+type PolicyError struct {
+	// Command is the full command that was submitted.
+	Command string
+
+	// Rejected is the individual command that violated the policy.
+	Rejected string
+
+	// Rule is the policy rule that rejected the command. It is empty
+	// if the command was rejected because Policy.DenyByDefault is set.
+	Rule string
+}
+
+func (o *PolicyError) Error() string {
+	if o.Rule == "" {
+		return fmt.Sprintf("command '%s' is not allowed by policy - it does not match any allow rule",
+			o.Rejected)
+	}
+
+	return fmt.Sprintf("command '%s' is not allowed by policy - it matches deny rule '%s'",
+		o.Rejected, o.Rule)
+}
+
+// Policy decides which radare2 commands may be executed.
+//
+// A command line is first split into the individual commands radare2
+// would run. This includes commands separated by ';' or new lines,
+// commands inside backticks, '@@c:' iterator commands, and temporary
+// eval changes made with '@e:'. Pipes and output redirections are
+// represented as commands starting with '|' and '>' respectively, and
+// temporary modifiers that read files as commands starting with '@f:'
+// and '@@.'. Command lines containing backslash-escaped command
+// separators (for example, '\;') are rejected.
+//
+// Each command is then matched against the Allow and Deny prefix lists.
+// The longest matching prefix wins. If an allow rule and a deny rule
+// are the same length, the deny rule wins.
+type Policy struct {
+	// Allow is a list of command prefixes that may be executed.
+	Allow []string
+
+	// Deny is a list of command prefixes that may not be executed.
+	Deny []string
+
+	// DenyByDefault rejects commands that do not match any rule.
+	DenyByDefault bool
+}
+
+// Check returns a *PolicyError if the command line contains
+// a command that is not allowed by the policy.
+func (o *Policy) Check(command string) error {
+	cmds, err := splitCommands(command)
+	if err != nil {
+		return err
+	}
+
+	for _, cmd := range cmds {
+		allowed, rule := o.allowed(cmd)
+		if !allowed {
+			return &PolicyError{
+				Command:  command,
+				Rejected: cmd,
+				Rule:     rule,
+			}
+		}
+	}
+
+	return nil
+}
+
+func (o *Policy) allowed(cmd string) (bool, string) {
+	var allowLen int
+	for _, prefix := range o.Allow {
+		if strings.HasPrefix(cmd, prefix) && len(prefix) > allowLen {
+			allowLen = len(prefix)
+		}
+	}
+
+	var denyRule string
+	for _, prefix := range o.Deny {
+		if strings.HasPrefix(cmd, prefix) && len(prefix) > len(denyRule) {
+			denyRule = prefix
+		}
+	}
+
+	if denyRule != "" && len(denyRule) >= allowLen {
+		return false, denyRule
+	}
+
+	if allowLen > 0 {
+		return true, ""
+	}
+
+	return !o.DenyByDefault, ""
+}
+
+// NewReadOnlyPolicy returns a Policy that only allows commands which
+// read information from the current session, such as printing,
+// information, analysis, flag, comment, type, signature, search and
+// seek commands. Commands that do not match an allow rule are rejected.
+// This includes commands that execute shell commands, run scripts,
+// access the file system, write to files, open other files, change
+// eval variables, or drive a debugger. Pipes, output redirections and
+// temporary modifiers that read files ('@f:' and '@@.') are rejected
+// as well.
+//
+// Helpers that rely on rejected commands cannot be used with an Api
+// returned by NewPolicyApi using this policy. These include Eval's Get
+// and Set methods, WithEval, Search (which sets search eval variables),
+// Debugger, Emulator, Patcher, Projects, Types.LoadFile, Types.Define,
+// and Zignatures.Load and Zignatures.Save. Decompile cannot detect
+// decompiler plugins ('Lcj') and falls back to 'pdc'. Eval.List, Maps,
+// Sections, Functions, and the other analysis helpers only read
+// information and can be used.
+func NewReadOnlyPolicy() *Policy {
+	return &Policy{
+		Allow: []string{
+			"a",    // Analysis.
+			"i",    // Binary information.
+			"f",    // Flags.
+			"C",    // Comments and metadata.
+			"t",    // Types.
+			"z",    // Signatures.
+			"s",    // Seek.
+			"/",    // Search.
+			"x",    // Hexdump.
+			"pd",   // Disassembly and decompilation.
+			"pD",   // Disassembly of a number of bytes.
+			"pi",   // Instructions.
+			"pI",   // Instructions of a number of bytes.
+			"px",   // Hexdump.
+			"p8",   // Bytes.
+			"pa",   // Assemble and disassemble.
+			"pc",   // Bytes as source code.
+			"ph",   // Hashes.
+			"pj",   // Bytes as JSON.
+			"ps",   // Strings.
+			"pv",   // Values.
+			"p=",   // Bar charts and entropy.
+			"?v",   // Expressions.
+			"?x",   // Hexadecimal conversions.
+			"?b",   // Binary conversions.
+			"?e",   // Echo.
+			"oj",   // Open files.
+			"omj",  // IO maps.
+			"ej",   // Eval variables.
+			"e?",   // Eval variable descriptions.
+			"dmj",  // Debugger memory maps.
+			"agCj", // Global call graph.
+			"agfj", // Function graph.
+		},
+		Deny: []string{
+			"ag", // Graphs, which can be written to image files.
+			"iO", // Write binary information to a file.
+			"io", // Load binary information from a file.
+			"to", // Load types from a file.
+			"zo", // Load and save signatures to a file.
+			"zf", // Load and save FLIRT signatures.
+			"/F", // Search the contents of a file.
+		},
+		DenyByDefault: true,
+	}
+}
+
+type policyApi struct {
+	api    Api
+	policy *Policy
+}
+
+func (o *policyApi) Start() error {
+	return o.api.Start()
+}
+
+func (o *policyApi) Interrupt() error {
+	return o.api.Interrupt()
+}
+
+func (o *policyApi) Kill() {
+	o.api.Kill()
+}
+
+func (o *policyApi) OnStopped() chan StoppedInfo {
+	return o.api.OnStopped()
+}
+
+func (o *policyApi) Status() Status {
+	return o.api.Status()
+}
+
+func (o *policyApi) debuggeeExitStatus(pid int) (int, bool) {
+	reporter, isReporter := o.api.(debuggeeExitReporter)
+	if !isReporter {
+		return 0, false
+	}
+
+	return reporter.debuggeeExitStatus(pid)
+}
+
+func (o *policyApi) Execute(command string) (string, error) {
+	err := o.policy.Check(command)
+	if err != nil {
+		return "", err
+	}
+
+	return o.api.Execute(command)
+}
+
+func (o *policyApi) ExecuteToJson(command string, pointer interface{}) error {
+	err := o.policy.Check(command)
+	if err != nil {
+		return err
+	}
+
+	return o.api.ExecuteToJson(command, pointer)
+}
+
+func (o *policyApi) ExecuteToBytes(command string) ([]byte, error) {
+	err := o.policy.Check(command)
+	if err != nil {
+		return nil, err
+	}
+
+	return o.api.ExecuteToBytes(command)
+}
+
+func (o *policyApi) ExecuteStream(command string) (io.ReadCloser, error) {
+	err := o.policy.Check(command)
+	if err != nil {
+		return nil, err
+	}
+
+	return ExecuteStream(o.api, command)
+}
+
+// NewPolicyApi returns an Api that checks each command against the
+// provided Policy before passing it to api. Commands that are not
+// allowed are rejected with a *PolicyError and never reach radare2.
+func NewPolicyApi(api Api, policy *Policy) (Api, error) {
+	if api == nil {
+		return nil, errors.New("api is nil")
+	}
+
+	if policy == nil {
+		return nil, errors.New("policy is nil")
+	}
+
+	return &policyApi{
+		api:    api,
+		policy: policy,
+	}, nil
+}
+
+// splitCommands splits a radare2 command line into the individual
+// commands radare2 would execute.
+func splitCommands(line string) ([]string, error) {
+	var cmds []string
+	var current strings.Builder
+
+	flush := func() {
+		cmd := normalizeCommand(current.String())
+		if cmd != "" {
+			cmds = append(cmds, cmd)
+		}
+		current.Reset()
+	}
+
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch c {
+		case '\\':
+			if i+1 < len(line) && strings.IndexByte(";\n\r|>@`", line[i+1]) >= 0 {
+				return nil, errors.New("command contains an escaped command separator")
+			}
+
+			current.WriteByte(c)
+			if i+1 < len(line) {
+				i++
+				current.WriteByte(line[i])
+			}
+		case '"':
+			if strings.TrimSpace(current.String()) != "" {
+				current.WriteByte(c)
+				continue
+			}
+
+			end := indexUnescaped(line[i+1:], '"')
+			if end < 0 {
+				return nil, errors.New("command contains an unterminated quote")
+			}
+
+			quoted := line[i+1 : i+1+end]
+			nested, err := backtickCommands(quoted)
+			if err != nil {
+				return nil, err
+			}
+			cmds = append(cmds, nested...)
+
+			current.WriteString(quoted)
+			i += end + 1
+		case '`':
+			end := indexUnescaped(line[i+1:], '`')
+			if end < 0 {
+				return nil, errors.New("command contains an unterminated backtick")
+			}
+
+			nested, err := splitCommands(line[i+1 : i+1+end])
+			if err != nil {
+				return nil, err
+			}
+			cmds = append(cmds, nested...)
+
+			current.WriteString(line[i : i+2+end])
+			i += end + 1
+		case ';', '\n', '\r':
+			flush()
+		case '|', '>':
+			flush()
+
+			end := indexAny(line[i+1:], ";\n\r")
+			target := line[i+1 : i+1+end]
+			nested, err := backtickCommands(target)
+			if err != nil {
+				return nil, err
+			}
+			cmds = append(cmds, nested...)
+			cmds = append(cmds, string(c)+strings.TrimSpace(target))
+
+			i += end
+		case '@':
+			flush()
+
+			end := indexAny(line[i+1:], ";\n\r|>")
+			nested, err := tempModifierCommands(line[i : i+1+end])
+			if err != nil {
+				return nil, err
+			}
+			cmds = append(cmds, nested...)
+
+			i += end
+		default:
+			current.WriteByte(c)
+		}
+	}
+
+	flush()
+
+	return cmds, nil
+}
+
+// tempModifierCommands returns the commands executed by a chain of
+// temporary modifiers such as '@ addr', '@e:key=value' or '@@c:cmd'.
+// Modifiers that read files ('@f:file' and '@@.file') are returned
+// as commands starting with '@f:' and '@@.' respectively.
+func tempModifierCommands(modifiers string) ([]string, error) {
+	cmds, err := backtickCommands(modifiers)
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.HasPrefix(modifiers, "@@") {
+		spec := strings.TrimSpace(strings.TrimLeft(modifiers, "@"))
+		switch {
+		case strings.HasPrefix(spec, "c:"):
+			nested, err := splitCommands(spec[2:])
+			if err != nil {
+				return nil, err
+			}
+			cmds = append(cmds, nested...)
+		case strings.HasPrefix(spec, "."):
+			cmds = append(cmds, "@@"+spec)
+		}
+
+		return cmds, nil
+	}
+
+	for _, modifier := range strings.Split(modifiers, "@") {
+		modifier = strings.TrimSpace(modifier)
+		switch {
+		case strings.HasPrefix(modifier, "e:"):
+			cmds = append(cmds, "e "+modifier[2:])
+		case strings.HasPrefix(modifier, "f:"):
+			cmds = append(cmds, "@"+modifier)
+		}
+	}
+
+	return cmds, nil
+}
+
+// backtickCommands returns the commands found inside backticks in s.
+func backtickCommands(s string) ([]string, error) {
+	var cmds []string
+
+	for {
+		start := indexUnescaped(s, '`')
+		if start < 0 {
+			return cmds, nil
+		}
+
+		end := indexUnescaped(s[start+1:], '`')
+		if end < 0 {
+			return nil, errors.New("command contains an unterminated backtick")
+		}
+
+		nested, err := splitCommands(s[start+1 : start+1+end])
+		if err != nil {
+			return nil, err
+		}
+		cmds = append(cmds, nested...)
+
+		s = s[start+2+end:]
+	}
+}
+
+// normalizeCommand removes the characters radare2 ignores or treats as
+// modifiers at the start of a command, such as white space, the literal
+// command prefix (') and repeat counts.
+func normalizeCommand(cmd string) string {
+	for {
+		trimmed := strings.TrimLeft(cmd, " \t'0123456789")
+		if trimmed == cmd {
+			return strings.TrimSpace(cmd)
+		}
+		cmd = trimmed
+	}
+}
+
+// indexUnescaped returns the index of the first occurrence of c in s
+// that is not escaped with a backslash, or -1 if there is none.
+func indexUnescaped(s string, c byte) int {
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case c:
+			return i
+		}
+	}
+
+	return -1
+}
+
+// indexAny is like strings.IndexAny, but returns len(s) rather than -1
+// if none of the characters are found.
+func indexAny(s string, chars string) int {
+	i := strings.IndexAny(s, chars)
+	if i < 0 {
+		return len(s)
+	}
+
+	return i
+}
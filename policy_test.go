@@ -0,0 +1,225 @@
+package radareutil
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestSplitCommands(t *testing.T) {
+	tests := []struct {
+		line     string
+		expected []string
+	}{
+		{line: "pdf", expected: []string{"pdf"}},
+		{line: "pd 10; w hello", expected: []string{"pd 10", "w hello"}},
+		{line: "pd 1\nw x", expected: []string{"pd 1", "w x"}},
+		{line: "pd|grep x", expected: []string{"pd", "|grep x"}},
+		{line: "pd > /tmp/x", expected: []string{"pd", ">/tmp/x"}},
+		{line: "?e `!id`", expected: []string{"!id", "?e `!id`"}},
+		{line: `"?e a;b"`, expected: []string{"?e a;b"}},
+		{line: "\"?e `w x`\"", expected: []string{"w x", "?e `w x`"}},
+		{line: `"pa nop" @ 0x10`, expected: []string{"pa nop"}},
+		{line: " pdf @ main", expected: []string{"pdf"}},
+		{line: "pd 1 @ `!id`", expected: []string{"pd 1", "!id"}},
+		{line: "pd @@c:!id", expected: []string{"pd", "!id"}},
+		{line: "x @@ fcn.*", expected: []string{"x"}},
+		{line: "pd @e:cfg.sandbox=false", expected: []string{"pd", "e cfg.sandbox=false"}},
+		{line: "pd @ 0x10 @e:a=b @e:c=d", expected: []string{"pd", "e a=b", "e c=d"}},
+		{line: "px @f:/etc/passwd", expected: []string{"px", "@f:/etc/passwd"}},
+		{line: "pd @@.offsets.txt", expected: []string{"pd", "@@.offsets.txt"}},
+		{line: `?e a\b`, expected: []string{`?e a\b`}},
+		{line: "'!id", expected: []string{"!id"}},
+		{line: "10w x", expected: []string{"w x"}},
+		{line: "3'wx 90", expected: []string{"wx 90"}},
+		{line: "afl~main", expected: []string{"afl~main"}},
+	}
+
+	for _, test := range tests {
+		cmds, err := splitCommands(test.line)
+		if err != nil {
+			t.Errorf("splitCommands(%q) failed - %s", test.line, err.Error())
+			continue
+		}
+
+		if !reflect.DeepEqual(cmds, test.expected) {
+			t.Errorf("splitCommands(%q) = %q - expected %q", test.line, cmds, test.expected)
+		}
+	}
+}
+
+func TestSplitCommandsUnterminated(t *testing.T) {
+	for _, line := range []string{`"?e hello`, "?e `!id", "pd @ `!id", `?e a\;!id`, `?e a\|sh`} {
+		_, err := splitCommands(line)
+		if err == nil {
+			t.Errorf("splitCommands(%q) did not fail", line)
+		}
+	}
+}
+
+func TestTempModifierCommands(t *testing.T) {
+	tests := []struct {
+		modifiers string
+		expected  []string
+	}{
+		{modifiers: "@ 0x10", expected: nil},
+		{modifiers: "@@ fcn.*", expected: nil},
+		{modifiers: "@ `!id`", expected: []string{"!id"}},
+		{modifiers: "@@c:!id", expected: []string{"!id"}},
+		{modifiers: "@@c:pd;w x", expected: []string{"pd", "w x"}},
+		{modifiers: "@e:cfg.sandbox=false", expected: []string{"e cfg.sandbox=false"}},
+		{modifiers: "@ 0x10 @e:a=b @e:c=d", expected: []string{"e a=b", "e c=d"}},
+		{modifiers: "@ 0x10 @f:/etc/passwd", expected: []string{"@f:/etc/passwd"}},
+		{modifiers: "@@.offsets.txt", expected: []string{"@@.offsets.txt"}},
+	}
+
+	for _, test := range tests {
+		cmds, err := tempModifierCommands(test.modifiers)
+		if err != nil {
+			t.Errorf("tempModifierCommands(%q) failed - %s", test.modifiers, err.Error())
+			continue
+		}
+
+		if len(cmds) == 0 && len(test.expected) == 0 {
+			continue
+		}
+
+		if !reflect.DeepEqual(cmds, test.expected) {
+			t.Errorf("tempModifierCommands(%q) = %q - expected %q", test.modifiers, cmds, test.expected)
+		}
+	}
+}
+
+func TestNormalizeCommand(t *testing.T) {
+	tests := []struct {
+		cmd      string
+		expected string
+	}{
+		{cmd: "pdf", expected: "pdf"},
+		{cmd: "  pdf  ", expected: "pdf"},
+		{cmd: "\tpdf", expected: "pdf"},
+		{cmd: "'!id", expected: "!id"},
+		{cmd: "10w x", expected: "w x"},
+		{cmd: "3'wx 90", expected: "wx 90"},
+		{cmd: "'3 'w x", expected: "w x"},
+	}
+
+	for _, test := range tests {
+		result := normalizeCommand(test.cmd)
+		if result != test.expected {
+			t.Errorf("normalizeCommand(%q) = %q - expected %q", test.cmd, result, test.expected)
+		}
+	}
+}
+
+func TestReadOnlyPolicy(t *testing.T) {
+	policy := NewReadOnlyPolicy()
+
+	allowed := []string{
+		"pdf",
+		"pd 10 @ main",
+		"iSj",
+		"afl~main",
+		"oj",
+		"ej",
+		"ej cfg.debug",
+		"px 16 @ entry0",
+		"agCj",
+		"?e hello",
+	}
+	for _, cmd := range allowed {
+		err := policy.Check(cmd)
+		if err != nil {
+			t.Errorf("read-only policy denied '%s' - %s", cmd, err.Error())
+		}
+	}
+
+	denied := []string{
+		"!id",
+		"'!id",
+		"?e `!id`",
+		"pd @@c:!id",
+		"pd @e:cfg.sandbox=false",
+		"pd > /tmp/x",
+		"pd | grep x",
+		"w hello",
+		"10w x",
+		"e cfg.debug=true",
+		"mkdir /tmp/x",
+		"mv a b",
+		"mw /f data",
+		"md /",
+		"cd /",
+		"cat /etc/passwd",
+		"ls /",
+		"cf 4 /etc/passwd",
+		"*entry0=0x90909090",
+		"iOd",
+		"kd /tmp/x",
+		"js:r2.cmd('!id')",
+		"pfo /etc/passwd",
+		"px @f:/etc/passwd",
+		"pd @@.offsets.txt",
+		"Vp",
+		"agfw /tmp/x.png",
+		`?e a\;!id`,
+	}
+	for _, cmd := range denied {
+		err := policy.Check(cmd)
+		if err == nil {
+			t.Errorf("read-only policy allowed '%s'", cmd)
+		}
+	}
+}
+
+func TestReadOnlyPolicyMaps(t *testing.T) {
+	api, err := NewPolicyApi(&mapsApi{}, NewReadOnlyPolicy())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	maps, err := Maps(api)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(maps) != 2 || maps[0].Name != "fd: 3 +0x0 0x0 - 0xff r-x" {
+		t.Fatalf("unexpected maps %+v", maps)
+	}
+
+	if maps[0].End != 0x100 || !maps[0].Contains(0xff) || maps[0].Contains(0x100) {
+		t.Errorf("unexpected end of map %+v", maps[0])
+	}
+
+	if maps[1].End != math.MaxUint64 || !maps[1].Contains(math.MaxUint64) || maps[1].Contains(0xfffffffffffffeff) {
+		t.Errorf("unexpected end of map that ends at the last address %+v", maps[1])
+	}
+}
+
+// mapsApi responds to the commands executed by Maps.
+type mapsApi struct {
+	Api
+}
+
+func (o *mapsApi) ExecuteToBytes(command string) ([]byte, error) {
+	switch command {
+	case "ej":
+		return []byte(`{"cfg.debug":false,"asm.arch":"x86"}`), nil
+	case "omj":
+		return []byte(`[{"name":"fd: 3 +0x0 0x0 - 0xff r-x","fd":3,"from":0,"to":255,"perm":"r-x"},` +
+			`{"name":"fd: 4 +0x0 0xffffffffffffff00 - 0xffffffffffffffff rw-","fd":4,"from":18446744073709551360,"to":18446744073709551615,"perm":"rw-"}]`), nil
+	}
+
+	return nil, fmt.Errorf("unexpected command '%s'", command)
+}
+
+func (o *mapsApi) ExecuteToJson(command string, pointer interface{}) error {
+	raw, err := o.ExecuteToBytes(command)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(raw, pointer)
+}
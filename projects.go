@@ -0,0 +1,177 @@
+package radareutil
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var (
+	projectNameRegex = regexp.MustCompile(`^[a-zA-Z0-9_-][a-zA-Z0-9_.-]*$`)
+)
+
+// Projects manages radare2 projects, which preserve analysis, comments,
+// and flags across radare2 sessions. Projects are stored in the
+// directory specified by the 'dir.projects' eval variable.
+type Projects struct {
+	api Api
+}
+
+// Save saves the current session to the named project ('Ps'). radare2
+// reports failures to save a project on stderr rather than failing the
+// command, so the project's script file is verified to have been
+// written afterwards. This requires the directory specified by the
+// 'dir.projects' eval variable to be accessible on the local file
+// system, which is not the case for a remote HttpApi.
+func (o *Projects) Save(name string) error {
+	err := validateProjectName(name)
+	if err != nil {
+		return err
+	}
+
+	dir, err := o.dir()
+	if err != nil {
+		return fmt.Errorf("failed to get the projects directory - %s", err.Error())
+	}
+
+	before, existed := projectModTime(dir, name)
+
+	err = o.execute("Ps", name)
+	if err != nil {
+		return err
+	}
+
+	after, exists := projectModTime(dir, name)
+	if !exists || (existed && !after.After(before)) {
+		return fmt.Errorf("radare2 did not save project '%s' to '%s'", name, dir)
+	}
+
+	return nil
+}
+
+// Open opens the named project ('Po').
+func (o *Projects) Open(name string) error {
+	return o.execute("Po", name)
+}
+
+// Delete deletes the named project ('Pd').
+func (o *Projects) Delete(name string) error {
+	return o.execute("Pd", name)
+}
+
+// List returns the names of the saved projects ('Plj').
+func (o *Projects) List() ([]string, error) {
+	var names []string
+	err := o.api.ExecuteToJson("Plj", &names)
+	if err != nil {
+		return nil, err
+	}
+
+	return names, nil
+}
+
+// Current returns the name of the project that is currently open ('P').
+// It returns an empty string if no project is open.
+func (o *Projects) Current() (string, error) {
+	output, err := o.api.Execute("P")
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(output), nil
+}
+
+func (o *Projects) execute(command string, name string) error {
+	err := validateProjectName(name)
+	if err != nil {
+		return err
+	}
+
+	_, err = o.api.Execute(command + " " + name)
+	if err != nil {
+		return fmt.Errorf("failed to execute '%s %s' - %s", command, name, err.Error())
+	}
+
+	return nil
+}
+
+// dir returns the projects directory with a leading '~' expanded
+// to the current user's home directory.
+func (o *Projects) dir() (string, error) {
+	dir, err := NewEval(o.api).Get("dir.projects")
+	if err != nil {
+		return "", err
+	}
+
+	dir = strings.TrimSpace(dir)
+	if dir == "" {
+		return "", errors.New("the 'dir.projects' eval variable is empty")
+	}
+
+	if dir == "~" || strings.HasPrefix(dir, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to get home directory - %s", err.Error())
+		}
+		dir = filepath.Join(home, dir[1:])
+	}
+
+	return dir, nil
+}
+
+// NewProjects returns a Projects for the provided Api.
+func NewProjects(api Api) *Projects {
+	return &Projects{
+		api: api,
+	}
+}
+
+// SaveProjectAndKill saves the session to the named project and then
+// kills radare2. radare2 is not killed unless the project is confirmed
+// to have been saved (see Projects.Save), so that the analysis is not
+// lost. This allows a long analysis to be
+// resumed later by setting Radare2Config.Project.
+func SaveProjectAndKill(api Api, name string) error {
+	err := NewProjects(api).Save(name)
+	if err != nil {
+		return err
+	}
+
+	api.Kill()
+
+	return nil
+}
+
+// projectModTime returns the modification time of the named project's
+// script file. radare2 names the file 'rc.r2', or 'rc' in older
+// versions. The second return value is false if neither file exists.
+func projectModTime(dir string, name string) (time.Time, bool) {
+	var modTime time.Time
+	var exists bool
+
+	for _, fileName := range []string{"rc.r2", "rc"} {
+		info, err := os.Stat(filepath.Join(dir, name, fileName))
+		if err != nil {
+			continue
+		}
+
+		if !exists || info.ModTime().After(modTime) {
+			modTime = info.ModTime()
+		}
+		exists = true
+	}
+
+	return modTime, exists
+}
+
+func validateProjectName(name string) error {
+	if !projectNameRegex.MatchString(name) {
+		return fmt.Errorf("invalid project name '%s' - it may only contain letters, numbers, '_', '.', and '-', and may not start with '.'", name)
+	}
+
+	return nil
+}
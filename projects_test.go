@@ -0,0 +1,79 @@
+package radareutil
+
+import (
+	"testing"
+	"time"
+)
+
+// fakeRadare2Projects is a fake radare2 that only saves projects whose
+// names start with "ok". The project "existing" was saved previously.
+const fakeRadare2Projects = `#!/bin/sh
+projects="$(dirname "$0")/projects"
+mkdir -p "$projects/existing"
+touch -t 200001010000 "$projects/existing/rc.r2"
+printf '\000'
+while IFS= read -r line; do
+	case "$line" in
+	'"e dir.projects"')
+		printf '%s\n' "$projects"
+		;;
+	"Ps ok"*)
+		mkdir -p "$projects/${line#Ps }"
+		echo "# ${line#Ps }" > "$projects/${line#Ps }/rc.r2"
+		;;
+	"Ps "*)
+		echo "Cannot save project" >&2
+		;;
+	esac
+	printf '\000'
+done
+`
+
+func TestSaveProjectAndKill(t *testing.T) {
+	api, cleanup := startFakeCliApi(t, fakeRadare2Projects, 10*time.Second)
+	defer cleanup()
+
+	for _, name := range []string{"broken", "existing"} {
+		err := SaveProjectAndKill(api, name)
+		if err == nil {
+			t.Fatalf("saving project '%s' that radare2 did not save succeeded", name)
+		}
+
+		if state := api.Status().State; state != Running {
+			t.Fatalf("radare2 was killed after failing to save project '%s' - state is %s", name, state)
+		}
+	}
+
+	projects := NewProjects(api)
+	for i := 0; i < 2; i++ {
+		err := projects.Save("ok-project")
+		if err != nil {
+			t.Fatalf("failed to save project on attempt %d - %s", i+1, err.Error())
+		}
+	}
+
+	err := SaveProjectAndKill(api, "ok-project")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if state := api.Status().State; state == Running {
+		t.Fatal("radare2 was not killed after saving a project")
+	}
+}
+
+func TestValidateProjectName(t *testing.T) {
+	for _, name := range []string{"project", "my-project_1.0", "_x"} {
+		err := validateProjectName(name)
+		if err != nil {
+			t.Errorf("valid project name '%s' was rejected - %s", name, err.Error())
+		}
+	}
+
+	for _, name := range []string{"", ".", "..", ".hidden", "a/b", "a b", "a;b"} {
+		err := validateProjectName(name)
+		if err == nil {
+			t.Errorf("invalid project name '%s' was accepted", name)
+		}
+	}
+}
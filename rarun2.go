@@ -0,0 +1,254 @@
+package radareutil
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// rarun2SpecialPrefixes are the characters that cause rarun2 to
+// interpret a value rather than use it as is. Quotes start escaped
+// strings, '@' reads a file, '`' and '!' execute shell commands, ':'
+// decodes hex, and '%' parses a number.
+const rarun2SpecialPrefixes = "'\"@`!:%"
+
+// Rarun2Profile is a rarun2 profile, which describes how a program
+// should be launched. Refer to 'man rarun2' for more information.
+//
+// rarun2 interprets values that start with certain characters. For
+// example, "@file" is replaced with the contents of file, and "`cmd`"
+// is replaced with the output of a shell command. Values, including
+// environment variable values, that start with one of the characters
+// in rarun2SpecialPrefixes are rejected by Validate.
+type Rarun2Profile struct {
+	// Program is the path to the program to run ('program').
+	Program string
+
+	// Args are the arguments passed to the program ('arg1', 'arg2', ...).
+	Args []string
+
+	// ClearEnv clears the environment before Env is applied ('clearenv').
+	ClearEnv bool
+
+	// Env is a list of environment variables in the form "KEY=value"
+	// ('setenv').
+	Env []string
+
+	// Stdin, Stdout, and Stderr are file paths that the program's
+	// standard input, output, and error are redirected to ('stdin',
+	// 'stdout', and 'stderr').
+	Stdin  string
+	Stdout string
+	Stderr string
+
+	// Input is a string that is written to the program's standard
+	// input ('input').
+	Input string
+
+	// Chdir is the directory to run the program in ('chdir').
+	Chdir string
+
+	// Chroot is the directory to chroot into before running the
+	// program ('chroot').
+	Chroot string
+
+	// Setuid and Setgid are the user and group to run the program as
+	// ('setuid' and 'setgid').
+	Setuid string
+	Setgid string
+
+	// Timeout is the maximum amount of time the program may run for
+	// ('timeout'). It is rounded up to the nearest second.
+	Timeout time.Duration
+
+	// TimeoutSignal is the signal sent to the program when Timeout
+	// is exceeded ('timeoutsig').
+	TimeoutSignal int
+
+	// DisableAslr disables address space layout randomization ('aslr').
+	DisableAslr bool
+
+	// Preload is a library to preload into the program ('preload').
+	Preload string
+
+	// Libpath is a directory to search for libraries ('libpath').
+	Libpath string
+
+	// MaxStack, MaxProc, and MaxFd limit the program's stack size,
+	// number of processes, and number of open files ('maxstack',
+	// 'maxproc', and 'maxfd').
+	MaxStack int
+	MaxProc  int
+	MaxFd    int
+
+	// Core enables core dumps ('core').
+	Core bool
+}
+
+func (o *Rarun2Profile) Validate() error {
+	strs := []string{o.Program, o.Stdin, o.Stdout, o.Stderr, o.Input,
+		o.Chdir, o.Chroot, o.Setuid, o.Setgid, o.Preload, o.Libpath}
+	strs = append(strs, o.Args...)
+	strs = append(strs, o.Env...)
+
+	for _, str := range strs {
+		if strings.ContainsAny(str, "\r\n") {
+			return fmt.Errorf("rarun2 profile value '%s' contains a new line", str)
+		}
+	}
+
+	for _, env := range o.Env {
+		if !strings.Contains(env, "=") {
+			return fmt.Errorf("rarun2 profile environment variable '%s' is not in the form KEY=value", env)
+		}
+
+		strs = append(strs, env[strings.Index(env, "=")+1:])
+	}
+
+	for _, str := range strs {
+		if str != "" && strings.IndexByte(rarun2SpecialPrefixes, str[0]) >= 0 {
+			return fmt.Errorf("rarun2 profile value '%s' starts with '%c', which rarun2 interprets", str, str[0])
+		}
+	}
+
+	if o.Timeout < 0 {
+		return errors.New("rarun2 profile timeout is negative")
+	}
+
+	if o.TimeoutSignal != 0 && o.Timeout == 0 {
+		return errors.New("rarun2 profile timeout signal requires a timeout")
+	}
+
+	return nil
+}
+
+// directives returns the profile's directives in the form "key=value".
+func (o *Rarun2Profile) directives() []string {
+	var directives []string
+	add := func(key string, value string) {
+		directives = append(directives, key+"="+value)
+	}
+
+	if o.Program != "" {
+		add("program", o.Program)
+	}
+
+	for i, arg := range o.Args {
+		add("arg"+strconv.Itoa(i+1), arg)
+	}
+
+	if o.ClearEnv {
+		add("clearenv", "true")
+	}
+
+	for _, env := range o.Env {
+		add("setenv", env)
+	}
+
+	strs := []struct {
+		key   string
+		value string
+	}{
+		{key: "stdin", value: o.Stdin},
+		{key: "stdout", value: o.Stdout},
+		{key: "stderr", value: o.Stderr},
+		{key: "input", value: o.Input},
+		{key: "chdir", value: o.Chdir},
+		{key: "chroot", value: o.Chroot},
+		{key: "setuid", value: o.Setuid},
+		{key: "setgid", value: o.Setgid},
+		{key: "preload", value: o.Preload},
+		{key: "libpath", value: o.Libpath},
+	}
+
+	for _, str := range strs {
+		if str.value != "" {
+			add(str.key, str.value)
+		}
+	}
+
+	if o.Timeout > 0 {
+		seconds := o.Timeout / time.Second
+		if o.Timeout%time.Second > 0 {
+			seconds++
+		}
+		add("timeout", strconv.Itoa(int(seconds)))
+	}
+
+	if o.TimeoutSignal != 0 {
+		add("timeoutsig", strconv.Itoa(o.TimeoutSignal))
+	}
+
+	if o.DisableAslr {
+		add("aslr", "no")
+	}
+
+	ints := []struct {
+		key   string
+		value int
+	}{
+		{key: "maxstack", value: o.MaxStack},
+		{key: "maxproc", value: o.MaxProc},
+		{key: "maxfd", value: o.MaxFd},
+	}
+
+	for _, i := range ints {
+		if i.value > 0 {
+			add(i.key, strconv.Itoa(i.value))
+		}
+	}
+
+	if o.Core {
+		add("core", "true")
+	}
+
+	return directives
+}
+
+// Encode validates the profile and writes it to w in the .rr2 format.
+func (o *Rarun2Profile) Encode(w io.Writer) error {
+	err := o.Validate()
+	if err != nil {
+		return err
+	}
+
+	buff := bufio.NewWriter(w)
+
+	buff.WriteString("#!/usr/bin/env rarun2\n")
+	for _, directive := range o.directives() {
+		buff.WriteString(directive)
+		buff.WriteString("\n")
+	}
+
+	return buff.Flush()
+}
+
+// String returns the profile in the .rr2 format. It does not
+// validate the profile.
+func (o *Rarun2Profile) String() string {
+	return "#!/usr/bin/env rarun2\n" + strings.Join(o.directives(), "\n") + "\n"
+}
+
+// writeTempRarun2Profile writes the profile to a temporary file and
+// returns its path. The caller is responsible for removing the file.
+func writeTempRarun2Profile(profile *Rarun2Profile) (string, error) {
+	f, err := ioutil.TempFile("", "radareutil-*.rr2")
+	if err != nil {
+		return "", err
+	}
+
+	err = profile.Encode(f)
+	f.Close()
+	if err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+
+	return f.Name(), nil
+}
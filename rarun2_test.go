@@ -0,0 +1,51 @@
+package radareutil
+
+import (
+	"testing"
+)
+
+func TestRarun2ProfileSpecialValues(t *testing.T) {
+	rejected := []*Rarun2Profile{
+		{Program: "/bin/true", Args: []string{"`id`"}},
+		{Program: "/bin/true", Args: []string{"ok", "@/etc/passwd"}},
+		{Program: "/bin/true", Args: []string{"'quoted'"}},
+		{Program: "/bin/true", Args: []string{"\"quoted\""}},
+		{Program: "/bin/true", Args: []string{":41414141"}},
+		{Program: "/bin/true", Args: []string{"%1234"}},
+		{Program: "/bin/true", Env: []string{"HOME=`id`"}},
+		{Program: "/bin/true", Env: []string{"HOME=@/etc/passwd"}},
+		{Program: "/bin/true", Stdin: "!cat /etc/passwd"},
+		{Program: "/bin/true", Input: "@/etc/passwd"},
+	}
+
+	for _, profile := range rejected {
+		err := profile.Validate()
+		if err == nil {
+			t.Errorf("profile with special values was accepted - %+v", profile)
+		}
+	}
+
+	accepted := &Rarun2Profile{
+		Program: "/bin/true",
+		Args:    []string{"--flag", "user@example.com", "a`b`", ""},
+		Env:     []string{"A=b@c", "EMPTY="},
+		Stdin:   "/dev/null",
+	}
+
+	err := accepted.Validate()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDebugProgramSpecialValues(t *testing.T) {
+	program := &DebugProgram{
+		Path: "/bin/true",
+		Args: []string{"`id`"},
+	}
+
+	err := program.Validate()
+	if err == nil {
+		t.Fatal("debug program with a special argument was accepted")
+	}
+}
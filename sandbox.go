@@ -0,0 +1,200 @@
+package radareutil
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// SandboxConfig configures restrictions that are applied to the radare2
+// process when it is started. These are intended to limit the damage
+// a hostile sample can do if it exploits a bug in radare2.
+//
+// Aside from Radare2Sandbox, these options are currently only supported
+// on Linux. Starting radare2 with any of them set on another operating
+// system will fail.
+//
+// Resource limits, NoNewPrivileges, and Seccomp can only be applied by
+// the process itself. They require LauncherPath to be set to a program
+// that calls RunSandboxLauncher, such as cmd/r2sandbox.
+type SandboxConfig struct {
+	// LauncherPath is the path to the program that applies resource
+	// limits, NoNewPrivileges, and Seccomp before executing radare2.
+	// The program must call RunSandboxLauncher when IsSandboxLauncher
+	// returns true. It can be the current program, as long as it does
+	// so at the start of main.
+	LauncherPath string
+
+	// CpuTimeLimit is the maximum amount of CPU time radare2 may use
+	// (RLIMIT_CPU). It is rounded up to the nearest second.
+	CpuTimeLimit time.Duration
+
+	// AddressSpaceLimit is the maximum size of radare2's virtual
+	// memory in bytes (RLIMIT_AS).
+	AddressSpaceLimit uint64
+
+	// OpenFilesLimit is the maximum number of file descriptors
+	// radare2 may have open (RLIMIT_NOFILE).
+	OpenFilesLimit uint64
+
+	// NewUserNamespace starts radare2 in a new user namespace in which
+	// the current user and group are mapped to themselves.
+	NewUserNamespace bool
+
+	// NewMountNamespace starts radare2 in a new mount namespace.
+	NewMountNamespace bool
+
+	// NewNetworkNamespace starts radare2 in a new network namespace
+	// with no network interfaces configured. This cannot be used
+	// with the HTTP API.
+	NewNetworkNamespace bool
+
+	// NoNewPrivileges prevents radare2 and its children from gaining
+	// privileges through set-user-ID programs or file capabilities.
+	NoNewPrivileges bool
+
+	// Seccomp installs a seccomp filter that rejects system calls
+	// radare2 should never need when analyzing a file, such as
+	// loading kernel modules, tracing processes, and creating network
+	// sockets. This is hardening that reduces the kernel attack
+	// surface. It is not a sandbox against command execution, as it
+	// does not prevent radare2 from executing programs or forking.
+	// It implies NoNewPrivileges and cannot be used with the HTTP API
+	// or with a debug pid, debug program, or rarun2 profile.
+	Seccomp bool
+
+	// PrivateWorkingDir runs radare2 in a new, empty temporary
+	// directory that is deleted when the process exits. The radare2
+	// executable path, the program to debug, and command line
+	// arguments naming existing files are made absolute beforehand.
+	PrivateWorkingDir bool
+
+	// Radare2Sandbox enables radare2's own sandbox by setting the
+	// 'cfg.sandbox' eval variable. This disables shell commands and
+	// access to files outside of the working directory.
+	Radare2Sandbox bool
+}
+
+// osLevel returns true if the configuration requires restrictions that
+// are enforced by the operating system rather than by radare2.
+func (o *SandboxConfig) osLevel() bool {
+	return o.CpuTimeLimit > 0 ||
+		o.AddressSpaceLimit > 0 ||
+		o.OpenFilesLimit > 0 ||
+		o.NewUserNamespace ||
+		o.NewMountNamespace ||
+		o.NewNetworkNamespace ||
+		o.NoNewPrivileges ||
+		o.Seccomp ||
+		o.PrivateWorkingDir
+}
+
+// inProcess returns true if the configuration requires restrictions
+// that must be applied by the launcher.
+func (o *SandboxConfig) inProcess() bool {
+	return o.CpuTimeLimit > 0 ||
+		o.AddressSpaceLimit > 0 ||
+		o.OpenFilesLimit > 0 ||
+		o.NoNewPrivileges ||
+		o.Seccomp
+}
+
+func (o *SandboxConfig) validate(mode Mode) error {
+	if mode == Http && (o.NewNetworkNamespace || o.Seccomp) {
+		return errors.New("the http api cannot be used when network access is sandboxed")
+	}
+
+	if o.inProcess() && o.LauncherPath == "" {
+		return errors.New("a sandbox launcher path is required to apply resource limits, no new privileges, or seccomp")
+	}
+
+	return nil
+}
+
+// cpuTimeLimitSeconds returns CpuTimeLimit rounded up to the nearest second.
+func (o *SandboxConfig) cpuTimeLimitSeconds() uint64 {
+	seconds := uint64(o.CpuTimeLimit / time.Second)
+	if o.CpuTimeLimit%time.Second > 0 {
+		seconds++
+	}
+
+	return seconds
+}
+
+// resolveExecutable returns the absolute path to the executable, which
+// is searched for in PATH if it does not contain a path separator.
+func resolveExecutable(path string) (string, error) {
+	resolved, err := exec.LookPath(path)
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Abs(resolved)
+}
+
+// absoluteFilePath returns the absolute form of path if it is a relative
+// path to an existing file. Otherwise, path is returned unmodified.
+func absoluteFilePath(path string) string {
+	if path == "" || filepath.IsAbs(path) || strings.HasPrefix(path, "-") {
+		return path
+	}
+
+	_, err := os.Stat(path)
+	if err != nil {
+		return path
+	}
+
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return path
+	}
+
+	return abs
+}
+
+func absoluteFilePaths(paths []string) []string {
+	if paths == nil {
+		return nil
+	}
+
+	abs := make([]string, len(paths))
+	for i := range paths {
+		abs[i] = absoluteFilePath(paths[i])
+	}
+
+	return abs
+}
+
+// withAbsolutePaths returns a copy of the configuration in which paths
+// that are relative to the current working directory are made absolute.
+// This allows radare2 to be started in a different working directory.
+func (o *Radare2Config) withAbsolutePaths() (*Radare2Config, error) {
+	config := *o
+
+	var err error
+	config.ExecutablePath, err = resolveExecutable(o.ExecutablePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find radare2 executable - %s", err.Error())
+	}
+
+	config.CustomCliArgs = absoluteFilePaths(o.CustomCliArgs)
+	config.AdditionalCliArgs = absoluteFilePaths(o.AdditionalCliArgs)
+
+	if o.DebugProgram != nil {
+		debugProgram := *o.DebugProgram
+		debugProgram.Path = absoluteFilePath(debugProgram.Path)
+		config.DebugProgram = &debugProgram
+	}
+
+	if o.Rarun2Profile != nil {
+		profile := *o.Rarun2Profile
+		profile.Program = absoluteFilePath(profile.Program)
+		config.Rarun2Profile = &profile
+	}
+
+	return &config, nil
+}
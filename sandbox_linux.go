@@ -0,0 +1,245 @@
+package radareutil
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	// sandboxInitEnv is the environment variable used to pass
+	// a sandboxInit to the launcher.
+	sandboxInitEnv = "RADAREUTIL_SANDBOX_INIT"
+
+	prSetNoNewPrivs = 38
+)
+
+// sandboxInit contains the restrictions that must be applied from inside
+// the new process before radare2 is executed.
+type sandboxInit struct {
+	Path              string `json:"path"`
+	CpuSeconds        uint64 `json:"cpu_seconds"`
+	AddressSpaceLimit uint64 `json:"address_space_limit"`
+	OpenFilesLimit    uint64 `json:"open_files_limit"`
+	NoNewPrivileges   bool   `json:"no_new_privileges"`
+	Seccomp           bool   `json:"seccomp"`
+}
+
+// IsSandboxLauncher returns true if the current process was started
+// as a sandbox launcher by SandboxConfig.LauncherPath.
+func IsSandboxLauncher() bool {
+	_, isSet := os.LookupEnv(sandboxInitEnv)
+	return isSet
+}
+
+// RunSandboxLauncher applies the restrictions that radare2 was started
+// with and replaces the current process with radare2. It only returns
+// if that fails, in which case the program should exit with a non-zero
+// exit status.
+func RunSandboxLauncher() error {
+	raw, isSet := os.LookupEnv(sandboxInitEnv)
+	if !isSet {
+		return errors.New("the current process was not started as a sandbox launcher")
+	}
+
+	var config sandboxInit
+	err := json.Unmarshal([]byte(raw), &config)
+	if err != nil {
+		return fmt.Errorf("failed to decode sandbox configuration - %s", err.Error())
+	}
+
+	// no_new_privs and seccomp filters are per-thread attributes that
+	// are inherited by the program that this thread executes.
+	runtime.LockOSThread()
+
+	rlimits := []struct {
+		resource int
+		value    uint64
+	}{
+		{resource: syscall.RLIMIT_CPU, value: config.CpuSeconds},
+		{resource: syscall.RLIMIT_AS, value: config.AddressSpaceLimit},
+		{resource: syscall.RLIMIT_NOFILE, value: config.OpenFilesLimit},
+	}
+
+	for _, rlimit := range rlimits {
+		if rlimit.value == 0 {
+			continue
+		}
+
+		err := syscall.Setrlimit(rlimit.resource, &syscall.Rlimit{
+			Cur: rlimit.value,
+			Max: rlimit.value,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to set resource limit %d - %s", rlimit.resource, err.Error())
+		}
+	}
+
+	if config.NoNewPrivileges || config.Seccomp {
+		_, _, errno := syscall.RawSyscall(syscall.SYS_PRCTL, prSetNoNewPrivs, 1, 0)
+		if errno != 0 {
+			return fmt.Errorf("failed to set no_new_privs - %s", errno.Error())
+		}
+	}
+
+	if config.Seccomp {
+		err := installSeccompFilter()
+		if err != nil {
+			return fmt.Errorf("failed to install seccomp filter - %s", err.Error())
+		}
+	}
+
+	env := os.Environ()
+	for i := range env {
+		if strings.HasPrefix(env[i], sandboxInitEnv+"=") {
+			env = append(env[:i], env[i+1:]...)
+			break
+		}
+	}
+
+	err = syscall.Exec(config.Path, os.Args, env)
+	return fmt.Errorf("failed to execute '%s' - %s", config.Path, err.Error())
+}
+
+func applySandbox(cmd *exec.Cmd, config *SandboxConfig) (func(), error) {
+	cleanup := func() {}
+
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+
+	if config.NewUserNamespace {
+		cmd.SysProcAttr.Cloneflags |= syscall.CLONE_NEWUSER
+		cmd.SysProcAttr.UidMappings = []syscall.SysProcIDMap{
+			{ContainerID: os.Getuid(), HostID: os.Getuid(), Size: 1},
+		}
+		cmd.SysProcAttr.GidMappings = []syscall.SysProcIDMap{
+			{ContainerID: os.Getgid(), HostID: os.Getgid(), Size: 1},
+		}
+	}
+
+	if config.NewMountNamespace {
+		cmd.SysProcAttr.Cloneflags |= syscall.CLONE_NEWNS
+	}
+
+	if config.NewNetworkNamespace {
+		cmd.SysProcAttr.Cloneflags |= syscall.CLONE_NEWNET
+	}
+
+	if config.inProcess() {
+		r2Path, err := resolveExecutable(cmd.Path)
+		if err != nil {
+			return nil, err
+		}
+
+		launcherPath, err := resolveExecutable(config.LauncherPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find sandbox launcher - %s", err.Error())
+		}
+
+		raw, err := json.Marshal(sandboxInit{
+			Path:              r2Path,
+			CpuSeconds:        config.cpuTimeLimitSeconds(),
+			AddressSpaceLimit: config.AddressSpaceLimit,
+			OpenFilesLimit:    config.OpenFilesLimit,
+			NoNewPrivileges:   config.NoNewPrivileges,
+			Seccomp:           config.Seccomp,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		// The launcher receives radare2's arguments, including
+		// argv[0], and passes them on unmodified.
+		cmd.Path = launcherPath
+		if cmd.Env == nil {
+			cmd.Env = os.Environ()
+		}
+		cmd.Env = append(cmd.Env, sandboxInitEnv+"="+string(raw))
+	}
+
+	if config.PrivateWorkingDir {
+		dir, err := ioutil.TempDir("", "radareutil-")
+		if err != nil {
+			return nil, fmt.Errorf("failed to create private working directory - %s", err.Error())
+		}
+
+		cmd.Dir = dir
+		cleanup = func() {
+			os.RemoveAll(dir)
+		}
+	}
+
+	return cleanup, nil
+}
+
+const (
+	seccompModeFilter       = 2
+	seccompRetKillProcess   = 0x80000000
+	seccompRetErrno         = 0x00050000
+	seccompRetAllow         = 0x7fff0000
+	seccompDataNrOffset     = 0
+	seccompDataArchOffset   = 4
+	seccompX32SyscallBitmap = 0x40000000
+)
+
+// installSeccompFilter installs a filter on the current thread that
+// fails the system calls in seccompDeniedSyscalls with EPERM.
+func installSeccompFilter() error {
+	if seccompAuditArch == 0 {
+		return fmt.Errorf("seccomp is not supported on %s", runtime.GOARCH)
+	}
+
+	filter := []syscall.SockFilter{
+		bpfStmt(syscall.BPF_LD|syscall.BPF_W|syscall.BPF_ABS, seccompDataArchOffset),
+		bpfJump(syscall.BPF_JMP|syscall.BPF_JEQ|syscall.BPF_K, seccompAuditArch, 1, 0),
+		bpfStmt(syscall.BPF_RET|syscall.BPF_K, seccompRetKillProcess),
+		bpfStmt(syscall.BPF_LD|syscall.BPF_W|syscall.BPF_ABS, seccompDataNrOffset),
+		bpfJump(syscall.BPF_JMP|syscall.BPF_JGE|syscall.BPF_K, seccompX32SyscallBitmap, 0, 1),
+		bpfStmt(syscall.BPF_RET|syscall.BPF_K, seccompRetKillProcess),
+	}
+
+	for _, nr := range seccompDeniedSyscalls {
+		filter = append(filter,
+			bpfJump(syscall.BPF_JMP|syscall.BPF_JEQ|syscall.BPF_K, uint32(nr), 0, 1),
+			bpfStmt(syscall.BPF_RET|syscall.BPF_K, seccompRetErrno|uint32(syscall.EPERM)))
+	}
+
+	filter = append(filter, bpfStmt(syscall.BPF_RET|syscall.BPF_K, seccompRetAllow))
+
+	program := syscall.SockFprog{
+		Len:    uint16(len(filter)),
+		Filter: &filter[0],
+	}
+
+	_, _, errno := syscall.RawSyscall(syscall.SYS_PRCTL, syscall.PR_SET_SECCOMP,
+		seccompModeFilter, uintptr(unsafe.Pointer(&program)))
+	if errno != 0 {
+		return errno
+	}
+
+	return nil
+}
+
+func bpfStmt(code uint16, k uint32) syscall.SockFilter {
+	return syscall.SockFilter{
+		Code: code,
+		K:    k,
+	}
+}
+
+func bpfJump(code uint16, k uint32, jt uint8, jf uint8) syscall.SockFilter {
+	return syscall.SockFilter{
+		Code: code,
+		Jt:   jt,
+		Jf:   jf,
+		K:    k,
+	}
+}
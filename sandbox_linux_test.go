@@ -0,0 +1,177 @@
+package radareutil
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSandboxEnvironment(t *testing.T) {
+	dir, err := ioutil.TempDir("", "radareutil-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeFakeRadare2(t, dir, fakeRadare2Shell)
+
+	err = ioutil.WriteFile(filepath.Join(dir, "sample.bin"), []byte("sample"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	launcher, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The radare2 path and file arguments are relative to the current
+	// working directory, which radare2 does not run in.
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.Chdir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	sandbox := &SandboxConfig{
+		LauncherPath:      launcher,
+		CpuTimeLimit:      90 * time.Second,
+		AddressSpaceLimit: 4 << 30,
+		OpenFilesLimit:    64,
+		NoNewPrivileges:   true,
+		Seccomp:           seccompAuditArch != 0,
+		PrivateWorkingDir: true,
+	}
+
+	api, err := NewCliApi(&Radare2Config{
+		ExecutablePath:    "./radare2",
+		AdditionalCliArgs: []string{"sample.bin"},
+		Sandbox:           sandbox,
+		CommandTimeout:    10 * time.Second,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = api.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer api.Kill()
+
+	expected := map[string]string{
+		"ulimit -t":                         "90",
+		"ulimit -v":                         "4194304",
+		"ulimit -n":                         "64",
+		"grep NoNewPrivs /proc/self/status": "NoNewPrivs:\t1",
+		`eval echo '$'$#`:                   filepath.Join(dir, "sample.bin"),
+		"cat \"$(eval echo '$'$#)\"":        "sample",
+	}
+	if sandbox.Seccomp {
+		expected["grep Seccomp: /proc/self/status"] = "Seccomp:\t2"
+	}
+
+	for cmd, value := range expected {
+		output, err := api.Execute(cmd)
+		if err != nil {
+			t.Fatalf("failed to execute '%s' - %s", cmd, err.Error())
+		}
+
+		if output != value {
+			t.Errorf("'%s' = %q - expected %q", cmd, output, value)
+		}
+	}
+
+	privateDir, err := api.Execute("pwd")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if privateDir == dir || privateDir == "" {
+		t.Fatalf("radare2 is not running in a private working directory - it is in '%s'", privateDir)
+	}
+
+	entries, err := ioutil.ReadDir(privateDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(entries) > 0 {
+		t.Errorf("private working directory contains %d entries", len(entries))
+	}
+
+	api.Kill()
+
+	_, err = os.Stat(privateDir)
+	if !os.IsNotExist(err) {
+		t.Errorf("private working directory was not deleted")
+	}
+}
+
+func TestSandboxNamespaces(t *testing.T) {
+	dir, err := ioutil.TempDir("", "radareutil-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	api, err := NewCliApi(&Radare2Config{
+		ExecutablePath: writeFakeRadare2(t, dir, fakeRadare2Shell),
+		Sandbox: &SandboxConfig{
+			NewUserNamespace:    true,
+			NewMountNamespace:   true,
+			NewNetworkNamespace: true,
+		},
+		CommandTimeout: 10 * time.Second,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = api.Start()
+	if err != nil {
+		t.Skipf("namespaces are not permitted - %s", err.Error())
+	}
+	defer api.Kill()
+
+	for _, namespace := range []string{"user", "mnt", "net"} {
+		current, err := os.Readlink("/proc/self/ns/" + namespace)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		output, err := api.Execute("readlink /proc/self/ns/" + namespace)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if output == current || !strings.HasPrefix(output, namespace+":") {
+			t.Errorf("radare2 is in namespace '%s' - expected a namespace other than '%s'", output, current)
+		}
+	}
+}
+
+func TestSandboxRequiresLauncher(t *testing.T) {
+	api, err := NewCliApi(&Radare2Config{
+		ExecutablePath: "radare2",
+		Sandbox: &SandboxConfig{
+			NoNewPrivileges: true,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = api.Start()
+	if err == nil {
+		api.Kill()
+		t.Fatal("radare2 was started without a sandbox launcher")
+	}
+}
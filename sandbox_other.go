@@ -0,0 +1,30 @@
+//go:build !linux
+// +build !linux
+
+package radareutil
+
+import (
+	"errors"
+	"os/exec"
+)
+
+func applySandbox(cmd *exec.Cmd, config *SandboxConfig) (func(), error) {
+	if config.osLevel() {
+		return nil, errors.New("operating system sandbox options are only supported on linux")
+	}
+
+	return func() {}, nil
+}
+
+// IsSandboxLauncher returns true if the current process was started
+// as a sandbox launcher by SandboxConfig.LauncherPath.
+func IsSandboxLauncher() bool {
+	return false
+}
+
+// RunSandboxLauncher applies the restrictions that radare2 was started
+// with and replaces the current process with radare2. It is only
+// supported on linux.
+func RunSandboxLauncher() error {
+	return errors.New("the sandbox launcher is only supported on linux")
+}
@@ -0,0 +1,83 @@
+package radareutil
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// fakeRadare2Shell is a fake radare2 that speaks the '-q -0' protocol.
+// Each command is evaluated as a shell command.
+const fakeRadare2Shell = `#!/bin/sh
+printf '\000'
+while IFS= read -r line; do
+	eval "$line" 2>&1
+	printf '\000'
+done
+`
+
+func TestMain(m *testing.M) {
+	// The test binary is its own sandbox launcher.
+	if IsSandboxLauncher() {
+		err := RunSandboxLauncher()
+		os.Stderr.WriteString(err.Error() + "\n")
+		os.Exit(126)
+	}
+
+	os.Exit(m.Run())
+}
+
+// writeFakeRadare2 writes a fake radare2 shell script to dir and returns
+// its path.
+func writeFakeRadare2(t *testing.T, dir string, script string) string {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake radare2 scripts require a unix shell")
+	}
+
+	path := filepath.Join(dir, "radare2")
+	err := ioutil.WriteFile(path, []byte(script), 0700)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestSeccompWithDebugTarget(t *testing.T) {
+	configs := []*Radare2Config{
+		{DebugPid: 1},
+		{DebugProgram: &DebugProgram{Path: "/bin/true"}},
+		{Rarun2Profile: &Rarun2Profile{Program: "/bin/true"}},
+	}
+
+	for i, config := range configs {
+		config.ExecutablePath = "radare2"
+		config.Sandbox = &SandboxConfig{
+			Seccomp:      true,
+			LauncherPath: "r2sandbox",
+		}
+
+		err := config.Validate()
+		if err == nil || !strings.Contains(err.Error(), "seccomp") {
+			t.Errorf("config %d with a debug target was not rejected because of seccomp - %v", i, err)
+		}
+	}
+}
+
+func TestRadare2SandboxWithCustomCliArgs(t *testing.T) {
+	config := &Radare2Config{
+		ExecutablePath: "radare2",
+		CustomCliArgs:  []string{"-q", "-0"},
+		Sandbox: &SandboxConfig{
+			Radare2Sandbox: true,
+		},
+	}
+
+	err := config.Validate()
+	if err == nil {
+		t.Fatal("custom cli args were allowed to drop the radare2 sandbox")
+	}
+}
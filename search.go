@@ -0,0 +1,250 @@
+package radareutil
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type SearchKind string
+
+func (o SearchKind) String() string {
+	return string(o)
+}
+
+const (
+	// SearchBytes searches for a hex encoded byte sequence ('/xj').
+	// The query may contain white space and '.' nibble wildcards.
+	SearchBytes SearchKind = "bytes"
+
+	// SearchString searches for a string ('/j').
+	SearchString SearchKind = "string"
+
+	// SearchRegex searches for data matching a regular expression ('/ej').
+	SearchRegex SearchKind = "regex"
+
+	// SearchAssembly searches for instructions matching assembly,
+	// such as "jmp rax" ('/aj').
+	SearchAssembly SearchKind = "assembly"
+
+	// SearchRopGadgets searches for ROP gadgets ('/Rj'). The query
+	// optionally filters gadgets by the instructions they contain,
+	// such as "pop rdi,ret".
+	SearchRopGadgets SearchKind = "rop"
+)
+
+// SearchRequest describes a search. The search boundaries are applied
+// using radare2's 'search.*' eval variables, which are restored to their
+// original values when the search completes.
+type SearchRequest struct {
+	Kind  SearchKind
+	Query string
+
+	// In is where to search ('search.in'), such as "io.maps",
+	// "bin.sections", "dbg.maps", or "range". It defaults to
+	// "range" if From or To is set.
+	In string
+
+	// From and To are the addresses to search between
+	// ('search.from' and 'search.to'). Both must be set when
+	// searching a range.
+	From *uint64
+	To   *uint64
+
+	// Align only reports hits at addresses that are a multiple
+	// of the value ('search.align').
+	Align int
+
+	// MaxHits stops the search after this many hits ('search.maxhits').
+	MaxHits int
+}
+
+func (o *SearchRequest) command() (string, error) {
+	switch o.Kind {
+	case SearchBytes:
+		query := strings.Join(strings.Fields(o.Query), "")
+		if _, err := hex.DecodeString(strings.Replace(query, ".", "0", -1)); err != nil {
+			return "", fmt.Errorf("byte search query is not valid hex - %s", err.Error())
+		}
+		return "/xj " + query, nil
+	case SearchString:
+		return "/j " + o.Query, nil
+	case SearchRegex:
+		return "/ej /" + o.Query + "/", nil
+	case SearchAssembly:
+		return "/aj " + o.Query, nil
+	case SearchRopGadgets:
+		if o.Query == "" {
+			return "/Rj", nil
+		}
+		return "/Rj " + o.Query, nil
+	default:
+		return "", fmt.Errorf("unknown search kind '%s'", o.Kind.String())
+	}
+}
+
+func (o *SearchRequest) evalVars() (map[string]string, error) {
+	vars := make(map[string]string)
+
+	in := o.In
+	if in == "" && (o.From != nil || o.To != nil) {
+		in = "range"
+	}
+	if in != "" {
+		vars["search.in"] = in
+	}
+
+	if in == "range" {
+		if o.From == nil || o.To == nil {
+			return nil, errors.New("both from and to must be set when searching a range")
+		}
+
+		if *o.From > *o.To {
+			return nil, fmt.Errorf("search range start 0x%x is after its end 0x%x", *o.From, *o.To)
+		}
+	}
+
+	if o.From != nil {
+		vars["search.from"] = fmt.Sprintf("0x%x", *o.From)
+	}
+
+	if o.To != nil {
+		vars["search.to"] = fmt.Sprintf("0x%x", *o.To)
+	}
+
+	if o.Align > 0 {
+		vars["search.align"] = strconv.Itoa(o.Align)
+	}
+
+	if o.MaxHits > 0 {
+		vars["search.maxhits"] = strconv.Itoa(o.MaxHits)
+	}
+
+	return vars, nil
+}
+
+// SearchHit is a match found by Search.
+type SearchHit struct {
+	Addr uint64
+
+	// Size is the size of the match in bytes, if known.
+	Size int
+
+	// Type is the type of data radare2 reports for the match,
+	// such as "hexpair" or "string".
+	Type string
+
+	// Data is the matched data. For assembly and ROP gadget searches,
+	// it is the disassembly of the matched instructions.
+	Data string
+
+	// Instructions are the instructions of a ROP gadget.
+	Instructions []SearchInstruction
+}
+
+// SearchInstruction is an instruction in a ROP gadget.
+type SearchInstruction struct {
+	Addr   uint64 `json:"offset"`
+	Size   int    `json:"size"`
+	Opcode string `json:"opcode"`
+	Type   string `json:"type"`
+}
+
+type rawSearchHit struct {
+	Offset uint64 `json:"offset"`
+	Type   string `json:"type"`
+	Data   string `json:"data"`
+	Code   string `json:"code"`
+	Len    int    `json:"len"`
+}
+
+type rawRopGadget struct {
+	Opcodes []SearchInstruction `json:"opcodes"`
+	Size    int                 `json:"size"`
+}
+
+// Search executes the search described by request and returns its hits.
+func Search(api Api, request *SearchRequest) ([]SearchHit, error) {
+	if request == nil {
+		return nil, errors.New("search request is nil")
+	}
+
+	command, err := request.command()
+	if err != nil {
+		return nil, err
+	}
+
+	vars, err := request.evalVars()
+	if err != nil {
+		return nil, err
+	}
+
+	var hits []SearchHit
+	err = WithEval(api, vars, func() error {
+		var err error
+		hits, err = search(api, request.Kind, quoteCommand(command))
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return hits, nil
+}
+
+func search(api Api, kind SearchKind, command string) ([]SearchHit, error) {
+	if kind == SearchRopGadgets {
+		var gadgets []rawRopGadget
+		err := api.ExecuteToJson(command, &gadgets)
+		if err != nil {
+			return nil, err
+		}
+
+		hits := make([]SearchHit, 0, len(gadgets))
+		for _, gadget := range gadgets {
+			if len(gadget.Opcodes) == 0 {
+				continue
+			}
+
+			opcodes := make([]string, len(gadget.Opcodes))
+			for i := range gadget.Opcodes {
+				opcodes[i] = gadget.Opcodes[i].Opcode
+			}
+
+			hits = append(hits, SearchHit{
+				Addr:         gadget.Opcodes[0].Addr,
+				Size:         gadget.Size,
+				Type:         "gadget",
+				Data:         strings.Join(opcodes, "; "),
+				Instructions: gadget.Opcodes,
+			})
+		}
+
+		return hits, nil
+	}
+
+	var raws []rawSearchHit
+	err := api.ExecuteToJson(command, &raws)
+	if err != nil {
+		return nil, err
+	}
+
+	hits := make([]SearchHit, len(raws))
+	for i, raw := range raws {
+		data := raw.Data
+		if data == "" {
+			data = raw.Code
+		}
+
+		hits[i] = SearchHit{
+			Addr: raw.Offset,
+			Size: raw.Len,
+			Type: raw.Type,
+			Data: data,
+		}
+	}
+
+	return hits, nil
+}
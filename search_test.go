@@ -0,0 +1,48 @@
+package radareutil
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSearchRequestRangeFromZero(t *testing.T) {
+	from := uint64(0)
+	to := uint64(0x1000)
+
+	request := &SearchRequest{
+		Kind:  SearchBytes,
+		Query: "90",
+		From:  &from,
+		To:    &to,
+	}
+
+	vars, err := request.evalVars()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]string{
+		"search.in":   "range",
+		"search.from": "0x0",
+		"search.to":   "0x1000",
+	}
+	if !reflect.DeepEqual(vars, expected) {
+		t.Errorf("eval vars are %v - expected %v", vars, expected)
+	}
+}
+
+func TestSearchRequestIncompleteRange(t *testing.T) {
+	to := uint64(0x1000)
+
+	requests := []*SearchRequest{
+		{Kind: SearchBytes, Query: "90", To: &to},
+		{Kind: SearchBytes, Query: "90", In: "range"},
+	}
+
+	for _, request := range requests {
+		_, err := request.evalVars()
+		if err == nil {
+			t.Errorf("incomplete range %+v was accepted", request)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package radareutil
+
+import (
+	"syscall"
+)
+
+const (
+	seccompAuditArch = 0xc000003e // AUDIT_ARCH_X86_64
+)
+
+// seccompDeniedSyscalls lists the system calls rejected by the
+// sandbox seccomp filter.
+var seccompDeniedSyscalls = []uintptr{
+	syscall.SYS_PTRACE,
+	310, // process_vm_readv
+	311, // process_vm_writev
+	syscall.SYS_MOUNT,
+	syscall.SYS_UMOUNT2,
+	syscall.SYS_PIVOT_ROOT,
+	syscall.SYS_CHROOT,
+	syscall.SYS_UNSHARE,
+	308, // setns
+	syscall.SYS_SOCKET,
+	syscall.SYS_CONNECT,
+	syscall.SYS_BIND,
+	syscall.SYS_LISTEN,
+	syscall.SYS_ACCEPT,
+	syscall.SYS_ACCEPT4,
+	syscall.SYS_KEXEC_LOAD,
+	syscall.SYS_INIT_MODULE,
+	313, // finit_module
+	syscall.SYS_DELETE_MODULE,
+	syscall.SYS_REBOOT,
+	syscall.SYS_SWAPON,
+	syscall.SYS_SWAPOFF,
+	syscall.SYS_KEYCTL,
+	syscall.SYS_ADD_KEY,
+	syscall.SYS_REQUEST_KEY,
+	321, // bpf
+	syscall.SYS_PERF_EVENT_OPEN,
+}
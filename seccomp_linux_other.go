@@ -0,0 +1,12 @@
+//go:build linux && !amd64 && !arm64
+// +build linux,!amd64,!arm64
+
+package radareutil
+
+const (
+	// seccompAuditArch is zero on architectures that the sandbox
+	// seccomp filter does not support.
+	seccompAuditArch = 0
+)
+
+var seccompDeniedSyscalls []uintptr
@@ -0,0 +1,239 @@
+package radareutil
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Section is a section or segment as reported by 'iSj' and 'iSSj'.
+type Section struct {
+	Name  string `json:"name"`
+	Size  uint64 `json:"size"`
+	Vsize uint64 `json:"vsize"`
+	Perm  string `json:"perm"`
+	Paddr uint64 `json:"paddr"`
+	Vaddr uint64 `json:"vaddr"`
+
+	// Entropy is the section's entropy. It is only set if "entropy"
+	// was requested in SectionsOptions.Hashes.
+	Entropy float64 `json:"-"`
+
+	// Hashes maps each hash requested in SectionsOptions.Hashes
+	// to its value.
+	Hashes map[string]string `json:"-"`
+}
+
+// Contains returns true if addr is within the section's virtual
+// address range.
+func (o Section) Contains(addr uint64) bool {
+	return addr >= o.Vaddr && addr-o.Vaddr < o.Vsize
+}
+
+// SectionsOptions configures the information returned by Sections
+// and Segments.
+type SectionsOptions struct {
+	// Hashes is a list of hashes to calculate for each section,
+	// such as HashEntropy, HashMd5, or HashSha256.
+	Hashes []string
+}
+
+// Sections returns the sections of the binary that radare2 currently
+// has open. A nil options is equivalent to an empty SectionsOptions.
+func Sections(api Api, options *SectionsOptions) ([]Section, error) {
+	return sections(api, "iSj", options)
+}
+
+// Segments returns the segments of the binary that radare2 currently
+// has open. A nil options is equivalent to an empty SectionsOptions.
+func Segments(api Api, options *SectionsOptions) ([]Section, error) {
+	return sections(api, "iSSj", options)
+}
+
+func sections(api Api, command string, options *SectionsOptions) ([]Section, error) {
+	if options == nil {
+		options = &SectionsOptions{}
+	}
+
+	if len(options.Hashes) > 0 {
+		command = command + " " + strings.Join(options.Hashes, ",")
+	}
+
+	var raws []json.RawMessage
+	err := api.ExecuteToJson(command, &raws)
+	if err != nil {
+		return nil, err
+	}
+
+	sections := make([]Section, len(raws))
+	for i := range raws {
+		err := json.Unmarshal(raws[i], &sections[i])
+		if err != nil {
+			return nil, err
+		}
+
+		if len(options.Hashes) == 0 {
+			continue
+		}
+
+		var fields map[string]json.RawMessage
+		err = json.Unmarshal(raws[i], &fields)
+		if err != nil {
+			return nil, err
+		}
+
+		sections[i].Hashes = make(map[string]string)
+		for _, hash := range options.Hashes {
+			value, hasHash := fields[hash]
+			if !hasHash {
+				continue
+			}
+
+			str := strings.Trim(string(value), `"`)
+			sections[i].Hashes[hash] = str
+
+			if hash == "entropy" {
+				sections[i].Entropy, err = strconv.ParseFloat(str, 64)
+				if err != nil {
+					return nil, fmt.Errorf("failed to parse entropy of section '%s' - %s",
+						sections[i].Name, err.Error())
+				}
+			}
+		}
+	}
+
+	return sections, nil
+}
+
+// FindSection returns the section or segment that contains addr.
+func FindSection(sections []Section, addr uint64) (Section, bool) {
+	for _, section := range sections {
+		if section.Contains(addr) {
+			return section, true
+		}
+	}
+
+	return Section{}, false
+}
+
+// MemoryMap is a region of memory as reported by 'omj', or by 'dmj'
+// when radare2 is debugging a process.
+type MemoryMap struct {
+	Name string
+
+	// Start is the first address in the map.
+	Start uint64
+
+	// End is the address after the last address in the map. It is
+	// math.MaxUint64 for a map that ends at the last address, in which
+	// case the map contains math.MaxUint64 as well.
+	End uint64
+
+	Perm string
+
+	// Fd is the file descriptor of the file backing an IO map.
+	// It is not set for debugger maps.
+	Fd int
+}
+
+// Contains returns true if addr is within the map.
+func (o MemoryMap) Contains(addr uint64) bool {
+	return addr >= o.Start && (addr < o.End || o.End == math.MaxUint64)
+}
+
+type ioMap struct {
+	Fd   int    `json:"fd"`
+	From uint64 `json:"from"`
+	To   uint64 `json:"to"`
+	Perm string `json:"perm"`
+	Name string `json:"name"`
+}
+
+type debugMap struct {
+	Name    string `json:"name"`
+	Addr    uint64 `json:"addr"`
+	AddrEnd uint64 `json:"addr_end"`
+	Perm    string `json:"perm"`
+	File    string `json:"file"`
+}
+
+// Maps returns the memory maps of the current radare2 session. If
+// radare2 is debugging a process, the process' memory maps are returned
+// ('dmj'). Otherwise, radare2's IO maps are returned ('omj').
+func Maps(api Api) ([]MemoryMap, error) {
+	// The variable is read using 'ej' rather than 'e cfg.debug',
+	// which is rejected by the read-only policy.
+	vars, err := NewEval(api).List()
+	if err != nil {
+		return nil, err
+	}
+
+	debugging, err := strconv.ParseBool(vars["cfg.debug"])
+	if err != nil {
+		return nil, fmt.Errorf("eval variable 'cfg.debug' is not a boolean - %s", err.Error())
+	}
+
+	if debugging {
+		var debugMaps []debugMap
+		err := api.ExecuteToJson("dmj", &debugMaps)
+		if err != nil {
+			return nil, err
+		}
+
+		maps := make([]MemoryMap, len(debugMaps))
+		for i, m := range debugMaps {
+			name := m.Name
+			if m.File != "" {
+				name = m.File
+			}
+
+			maps[i] = MemoryMap{
+				Name:  name,
+				Start: m.Addr,
+				End:   m.AddrEnd,
+				Perm:  m.Perm,
+			}
+		}
+
+		return maps, nil
+	}
+
+	var ioMaps []ioMap
+	err = api.ExecuteToJson("omj", &ioMaps)
+	if err != nil {
+		return nil, err
+	}
+
+	maps := make([]MemoryMap, len(ioMaps))
+	for i, m := range ioMaps {
+		// 'omj' reports the last address in the map rather
+		// than the address after it.
+		end := m.To + 1
+		if m.To == math.MaxUint64 {
+			end = math.MaxUint64
+		}
+
+		maps[i] = MemoryMap{
+			Name:  m.Name,
+			Start: m.From,
+			End:   end,
+			Perm:  m.Perm,
+			Fd:    m.Fd,
+		}
+	}
+
+	return maps, nil
+}
+
+// FindMap returns the memory map that contains addr.
+func FindMap(maps []MemoryMap, addr uint64) (MemoryMap, bool) {
+	for _, m := range maps {
+		if m.Contains(addr) {
+			return m, true
+		}
+	}
+
+	return MemoryMap{}, false
+}
@@ -0,0 +1,148 @@
+package radareutil
+
+import (
+	"regexp"
+)
+
+// StringEntry is a string found in a binary by 'izj' or 'izzj'.
+type StringEntry struct {
+	Ordinal int    `json:"ordinal"`
+	Vaddr   uint64 `json:"vaddr"`
+	Paddr   uint64 `json:"paddr"`
+	Section string `json:"section"`
+
+	// Length is the number of characters in the string.
+	Length int `json:"length"`
+
+	// Size is the number of bytes the string occupies in the binary.
+	Size int `json:"size"`
+
+	// Type is the string's encoding, such as "ascii", "utf8",
+	// "utf16le", or "utf32le".
+	Type string `json:"type"`
+
+	// String is the string decoded by radare2.
+	String string `json:"string"`
+}
+
+// StringsOptions configures which strings are returned by Strings.
+type StringsOptions struct {
+	// WholeFile searches the entire file for strings ('izzj') rather
+	// than only the data sections ('izj').
+	WholeFile bool
+
+	// MinLength excludes strings with fewer characters.
+	MinLength int
+
+	// Encodings, if non-empty, excludes strings whose Type is not
+	// in the list.
+	Encodings []string
+
+	// Pattern, if non-nil, excludes strings that do not match it.
+	Pattern *regexp.Regexp
+}
+
+func (o *StringsOptions) command() string {
+	if o.WholeFile {
+		return "izzj"
+	}
+
+	return "izj"
+}
+
+func (o *StringsOptions) matches(entry *StringEntry) bool {
+	if entry.Length < o.MinLength {
+		return false
+	}
+
+	if len(o.Encodings) > 0 {
+		found := false
+		for _, encoding := range o.Encodings {
+			if entry.Type == encoding {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if o.Pattern != nil && !o.Pattern.MatchString(entry.String) {
+		return false
+	}
+
+	return true
+}
+
+// StringsIterator iterates over the strings returned by Strings.
+type StringsIterator struct {
+	stream  *JsonArrayStream
+	options *StringsOptions
+	current StringEntry
+	err     error
+}
+
+// Next advances to the next string that matches the StringsOptions.
+// It returns false when there are no more strings or an error occurred.
+// Callers should check Err after Next returns false.
+func (o *StringsIterator) Next() bool {
+	if o.err != nil {
+		return false
+	}
+
+	for o.stream.Next() {
+		var entry StringEntry
+		err := o.stream.Decode(&entry)
+		if err != nil {
+			o.err = err
+			return false
+		}
+
+		if o.options.matches(&entry) {
+			o.current = entry
+			return true
+		}
+	}
+
+	o.err = o.stream.Err()
+
+	return false
+}
+
+// Entry returns the current string.
+func (o *StringsIterator) Entry() StringEntry {
+	return o.current
+}
+
+// Err returns the first error that was encountered, if any.
+func (o *StringsIterator) Err() error {
+	return o.err
+}
+
+// Close stops the iteration. It must be called before the Api
+// can execute other commands.
+func (o *StringsIterator) Close() error {
+	return o.stream.Close()
+}
+
+// Strings returns an iterator over the strings in the binary that
+// radare2 currently has open. Strings are decoded one at a time, so
+// binaries containing a very large number of strings can be processed
+// without holding all of them in memory. A nil options is equivalent
+// to an empty StringsOptions.
+func Strings(api Api, options *StringsOptions) (*StringsIterator, error) {
+	if options == nil {
+		options = &StringsOptions{}
+	}
+
+	stream, err := ExecuteToJsonStream(api, options.command())
+	if err != nil {
+		return nil, err
+	}
+
+	return &StringsIterator{
+		stream:  stream,
+		options: options,
+	}, nil
+}
@@ -0,0 +1,85 @@
+package radareutil
+
+// Import is an imported symbol as reported by 'iij'.
+type Import struct {
+	Ordinal int    `json:"ordinal"`
+	Name    string `json:"name"`
+	Libname string `json:"libname"`
+	Bind    string `json:"bind"`
+	Type    string `json:"type"`
+	Plt     uint64 `json:"plt"`
+}
+
+// Symbol is a symbol as reported by 'isj' and 'iEj'.
+type Symbol struct {
+	Ordinal    int    `json:"ordinal"`
+	Name       string `json:"name"`
+	Demangled  string `json:"demname"`
+	FlagName   string `json:"flagname"`
+	RealName   string `json:"realname"`
+	Libname    string `json:"libname"`
+	Bind       string `json:"bind"`
+	Type       string `json:"type"`
+	Size       uint64 `json:"size"`
+	Vaddr      uint64 `json:"vaddr"`
+	Paddr      uint64 `json:"paddr"`
+	IsImported bool   `json:"is_imported"`
+}
+
+// Relocation is a relocation as reported by 'irj'.
+type Relocation struct {
+	Name      string `json:"name"`
+	Demangled string `json:"demname"`
+	Type      string `json:"type"`
+	Vaddr     uint64 `json:"vaddr"`
+	Paddr     uint64 `json:"paddr"`
+	IsIfunc   bool   `json:"is_ifunc"`
+}
+
+// Imports returns the symbols imported by the binary that radare2
+// currently has open.
+func Imports(api Api) ([]Import, error) {
+	var imports []Import
+	err := api.ExecuteToJson("iij", &imports)
+	if err != nil {
+		return nil, err
+	}
+
+	return imports, nil
+}
+
+// Exports returns the symbols exported by the binary that radare2
+// currently has open.
+func Exports(api Api) ([]Symbol, error) {
+	var exports []Symbol
+	err := api.ExecuteToJson("iEj", &exports)
+	if err != nil {
+		return nil, err
+	}
+
+	return exports, nil
+}
+
+// Symbols returns all of the symbols in the binary that radare2
+// currently has open.
+func Symbols(api Api) ([]Symbol, error) {
+	var symbols []Symbol
+	err := api.ExecuteToJson("isj", &symbols)
+	if err != nil {
+		return nil, err
+	}
+
+	return symbols, nil
+}
+
+// Relocations returns the relocations in the binary that radare2
+// currently has open.
+func Relocations(api Api) ([]Relocation, error) {
+	var relocations []Relocation
+	err := api.ExecuteToJson("irj", &relocations)
+	if err != nil {
+		return nil, err
+	}
+
+	return relocations, nil
+}
@@ -0,0 +1,468 @@
+package radareutil
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// StructMember is a member of a struct or union.
+type StructMember struct {
+	Name   string `json:"name"`
+	Type   string `json:"type"`
+	Offset uint64 `json:"offset"`
+}
+
+// StructType is a struct or union type as reported by 'tsj' or 'tuj'.
+type StructType struct {
+	Name    string
+	Members []StructMember
+}
+
+// EnumMember is a named value of an enum.
+type EnumMember struct {
+	Name  string `json:"name"`
+	Value int64  `json:"value"`
+}
+
+// EnumType is an enum type as reported by 'tej'.
+type EnumType struct {
+	Name    string
+	Members []EnumMember
+}
+
+// Typedef is a type alias as reported by 'ttj'.
+type Typedef struct {
+	Name string
+	Type string
+}
+
+// TypeLink is a type that is linked to an address, as reported by 'tlj'.
+type TypeLink struct {
+	Addr uint64
+	Type string
+}
+
+type rawNamedType struct {
+	Name    string          `json:"name"`
+	Members json.RawMessage `json:"members"`
+	Values  json.RawMessage `json:"values"`
+}
+
+// Types wraps radare2's type system commands ('t').
+type Types struct {
+	api Api
+}
+
+// LoadFile parses a C header file and loads the types it defines ('to').
+// The file is opened by radare2, so the path must be accessible to the
+// radare2 process. This is not the case for a remote HttpApi, and the
+// radare2 sandbox (SandboxConfig.Radare2Sandbox) rejects absolute paths.
+func (o *Types) LoadFile(filePath string) error {
+	_, err := o.api.Execute(quoteCommand("to " + filePath))
+	return err
+}
+
+// Define parses C type definitions, such as "struct foo { int bar; };",
+// and loads the types they define ('td'). The definitions are passed
+// to radare2 as a single command, so comments are removed and new
+// lines are replaced with spaces. Preprocessor directives, backticks,
+// and backslashes are not supported. Use LoadFile to load a header
+// file that contains them.
+func (o *Types) Define(code string) error {
+	definitions, err := typeDefinitionsCommand(code)
+	if err != nil {
+		return err
+	}
+
+	_, err = o.api.Execute(quoteCommand("td " + definitions))
+	return err
+}
+
+// Names returns the names of all loaded types ('tj').
+func (o *Types) Names() ([]string, error) {
+	var raw json.RawMessage
+	err := o.api.ExecuteToJson("tj", &raw)
+	if err != nil {
+		return nil, err
+	}
+
+	// Depending on the version of radare2, 'tj' is either a list
+	// of names or an object whose keys are the names.
+	var names []string
+	if json.Unmarshal(raw, &names) == nil {
+		return names, nil
+	}
+
+	var objects []map[string]interface{}
+	if json.Unmarshal(raw, &objects) == nil {
+		for _, object := range objects {
+			if name, ok := object["type"].(string); ok {
+				names = append(names, name)
+			}
+		}
+		return names, nil
+	}
+
+	var byName map[string]json.RawMessage
+	err = json.Unmarshal(raw, &byName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode types - %s", err.Error())
+	}
+
+	for name := range byName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names, nil
+}
+
+// Structs returns the loaded struct types ('tsj').
+func (o *Types) Structs() ([]StructType, error) {
+	return o.structs("tsj")
+}
+
+// Unions returns the loaded union types ('tuj').
+func (o *Types) Unions() ([]StructType, error) {
+	return o.structs("tuj")
+}
+
+func (o *Types) structs(command string) ([]StructType, error) {
+	var raws []rawNamedType
+	err := o.api.ExecuteToJson(command, &raws)
+	if err != nil {
+		return nil, err
+	}
+
+	types := make([]StructType, len(raws))
+	for i, raw := range raws {
+		members, err := decodeStructMembers(raw.Members)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode members of '%s' - %s", raw.Name, err.Error())
+		}
+
+		types[i] = StructType{
+			Name:    raw.Name,
+			Members: members,
+		}
+	}
+
+	return types, nil
+}
+
+// Enums returns the loaded enum types ('tej').
+func (o *Types) Enums() ([]EnumType, error) {
+	var raws []rawNamedType
+	err := o.api.ExecuteToJson("tej", &raws)
+	if err != nil {
+		return nil, err
+	}
+
+	types := make([]EnumType, len(raws))
+	for i, raw := range raws {
+		values := raw.Values
+		if len(values) == 0 {
+			values = raw.Members
+		}
+
+		members, err := decodeEnumMembers(values)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode values of '%s' - %s", raw.Name, err.Error())
+		}
+
+		types[i] = EnumType{
+			Name:    raw.Name,
+			Members: members,
+		}
+	}
+
+	return types, nil
+}
+
+// Typedefs returns the loaded type aliases ('ttj').
+func (o *Types) Typedefs() ([]Typedef, error) {
+	pairs, err := o.stringPairs("ttj", "name", "type")
+	if err != nil {
+		return nil, err
+	}
+
+	typedefs := make([]Typedef, len(pairs))
+	for i, pair := range pairs {
+		typedefs[i] = Typedef{
+			Name: pair[0],
+			Type: pair[1],
+		}
+	}
+
+	return typedefs, nil
+}
+
+// Link links the named type to addr ('tl').
+func (o *Types) Link(typeName string, addr uint64) error {
+	err := validateAnnotationName(typeName)
+	if err != nil {
+		return err
+	}
+
+	_, err = o.api.Execute(fmt.Sprintf("tl %s 0x%x", typeName, addr))
+	return err
+}
+
+// Unlink removes the type linked to addr ('tl-').
+func (o *Types) Unlink(addr uint64) error {
+	_, err := o.api.Execute(fmt.Sprintf("tl- 0x%x", addr))
+	return err
+}
+
+// Links returns the types that are linked to addresses ('tlj').
+func (o *Types) Links() ([]TypeLink, error) {
+	pairs, err := o.stringPairs("tlj", "addr", "type")
+	if err != nil {
+		return nil, err
+	}
+
+	links := make([]TypeLink, 0, len(pairs))
+	for _, pair := range pairs {
+		addr, err := strconv.ParseUint(pair[0], 0, 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse type link address '%s' - %s", pair[0], err.Error())
+		}
+
+		links = append(links, TypeLink{
+			Addr: addr,
+			Type: pair[1],
+		})
+	}
+
+	return links, nil
+}
+
+// stringPairs decodes the output of a command that is either an object
+// mapping keys to values, a list of such objects, or a list of objects
+// with the provided key and value fields.
+func (o *Types) stringPairs(command string, keyField string, valueField string) ([][2]string, error) {
+	var raw json.RawMessage
+	err := o.api.ExecuteToJson(command, &raw)
+	if err != nil {
+		return nil, err
+	}
+
+	var objects []map[string]interface{}
+	if unmarshalUseNumber(raw, &objects) != nil {
+		var object map[string]interface{}
+		err := unmarshalUseNumber(raw, &object)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode output of '%s' - %s", command, err.Error())
+		}
+		objects = []map[string]interface{}{object}
+	}
+
+	var pairs [][2]string
+	for _, object := range objects {
+		if key, hasKey := object[keyField]; hasKey {
+			pairs = append(pairs, [2]string{jsonScalarString(key), jsonScalarString(object[valueField])})
+			continue
+		}
+
+		keys := make([]string, 0, len(object))
+		for key := range object {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			pairs = append(pairs, [2]string{key, jsonScalarString(object[key])})
+		}
+	}
+
+	return pairs, nil
+}
+
+// NewTypes returns a Types for the provided Api.
+func NewTypes(api Api) *Types {
+	return &Types{
+		api: api,
+	}
+}
+
+// decodeStructMembers decodes struct members that are either a list
+// of StructMember or an object mapping member names to their types.
+func decodeStructMembers(raw json.RawMessage) ([]StructMember, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+
+	var members []StructMember
+	if json.Unmarshal(raw, &members) == nil {
+		return members, nil
+	}
+
+	var byName map[string]string
+	err := json.Unmarshal(raw, &byName)
+	if err != nil {
+		return nil, err
+	}
+
+	for name, typeName := range byName {
+		members = append(members, StructMember{
+			Name: name,
+			Type: typeName,
+		})
+	}
+	sort.Slice(members, func(i int, j int) bool {
+		return members[i].Name < members[j].Name
+	})
+
+	return members, nil
+}
+
+// decodeEnumMembers decodes enum members that are either a list of
+// EnumMember or an object mapping member names to their values.
+func decodeEnumMembers(raw json.RawMessage) ([]EnumMember, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+
+	var members []EnumMember
+
+	var list []struct {
+		Name  string      `json:"name"`
+		Value interface{} `json:"value"`
+	}
+	if unmarshalUseNumber(raw, &list) == nil {
+		for _, member := range list {
+			parsed, err := parseEnumValue(jsonScalarString(member.Value))
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse value of '%s' - %s", member.Name, err.Error())
+			}
+
+			members = append(members, EnumMember{
+				Name:  member.Name,
+				Value: parsed,
+			})
+		}
+
+		return members, nil
+	}
+
+	var byName map[string]interface{}
+	err := unmarshalUseNumber(raw, &byName)
+	if err != nil {
+		return nil, err
+	}
+
+	for name, value := range byName {
+		parsed, err := parseEnumValue(jsonScalarString(value))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse value of '%s' - %s", name, err.Error())
+		}
+
+		members = append(members, EnumMember{
+			Name:  name,
+			Value: parsed,
+		})
+	}
+	sort.Slice(members, func(i int, j int) bool {
+		return members[i].Value < members[j].Value
+	})
+
+	return members, nil
+}
+
+// parseEnumValue parses an enum member's value. Negative values are
+// reported either as is or as their two's complement, unsigned form.
+func parseEnumValue(str string) (int64, error) {
+	value, err := strconv.ParseInt(str, 0, 64)
+	if err == nil {
+		return value, nil
+	}
+
+	unsigned, uintErr := strconv.ParseUint(str, 0, 64)
+	if uintErr != nil {
+		return 0, err
+	}
+
+	return int64(unsigned), nil
+}
+
+// unmarshalUseNumber is like json.Unmarshal, but decodes
+// numbers as json.Number to avoid losing precision.
+func unmarshalUseNumber(data []byte, pointer interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	return decoder.Decode(pointer)
+}
+
+// jsonScalarString returns a decoded JSON string or number as a string.
+func jsonScalarString(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case nil:
+		return ""
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+// typeDefinitionsCommand converts C type definitions into a single
+// line that can be passed to 'td'.
+func typeDefinitionsCommand(code string) (string, error) {
+	if strings.ContainsAny(code, "`\\") {
+		return "", errors.New("type definitions may not contain backticks or backslashes")
+	}
+
+	var stripped strings.Builder
+	var quote byte
+	for i := 0; i < len(code); i++ {
+		c := code[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+			stripped.WriteByte(c)
+		case c == '"' || c == '\'':
+			quote = c
+			stripped.WriteByte(c)
+		case strings.HasPrefix(code[i:], "//"):
+			end := strings.IndexByte(code[i:], '\n')
+			if end < 0 {
+				end = len(code) - i
+			}
+			stripped.WriteByte(' ')
+			i += end - 1
+		case strings.HasPrefix(code[i:], "/*"):
+			end := strings.Index(code[i+2:], "*/")
+			if end < 0 {
+				return "", errors.New("type definitions contain an unterminated comment")
+			}
+			stripped.WriteByte(' ')
+			i += end + 3
+		default:
+			stripped.WriteByte(c)
+		}
+	}
+
+	for _, line := range strings.Split(stripped.String(), "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "#") {
+			return "", fmt.Errorf("type definitions may not contain preprocessor directives such as '%s'",
+				strings.TrimSpace(line))
+		}
+	}
+
+	definitions := strings.Join(strings.Fields(stripped.String()), " ")
+	if definitions == "" {
+		return "", errors.New("type definitions are empty")
+	}
+
+	return definitions, nil
+}
@@ -0,0 +1,75 @@
+package radareutil
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDecodeEnumMembers(t *testing.T) {
+	expected := []EnumMember{
+		{Name: "ERROR", Value: -1},
+		{Name: "OK", Value: 0},
+		{Name: "BIG", Value: 0x7fffffff},
+	}
+
+	raws := []string{
+		`{"OK":0,"ERROR":-1,"BIG":"0x7fffffff"}`,
+		`{"OK":"0x0","ERROR":"0xffffffffffffffff","BIG":2147483647}`,
+		`[{"name":"ERROR","value":-1},{"name":"OK","value":0},{"name":"BIG","value":2147483647}]`,
+		`[{"name":"ERROR","value":18446744073709551615},{"name":"OK","value":0},{"name":"BIG","value":"0x7fffffff"}]`,
+	}
+
+	for _, raw := range raws {
+		members, err := decodeEnumMembers(json.RawMessage(raw))
+		if err != nil {
+			t.Errorf("failed to decode %s - %s", raw, err.Error())
+			continue
+		}
+
+		if !reflect.DeepEqual(members, expected) {
+			t.Errorf("decoded %s as %+v - expected %+v", raw, members, expected)
+		}
+	}
+}
+
+func TestDefineTypes(t *testing.T) {
+	api := &scriptedApi{
+		outputs: map[string]string{
+			`"td struct point { int32_t x; int32_t y; }; enum color { RED = 1 };"`: "",
+		},
+	}
+
+	code := `// A point.
+struct point {
+	int32_t x; // The x coordinate.
+	int32_t y; /* The y
+	coordinate. */
+};
+
+enum color { RED = 1 };
+`
+
+	err := NewTypes(api).Define(code)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDefineTypesUnsupported(t *testing.T) {
+	api := &scriptedApi{}
+
+	for _, code := range []string{
+		"#include <stdint.h>\nstruct a { int b; };",
+		"struct a { int b; }; /* unterminated",
+		"struct a { int b; }; `!id`",
+		"struct a { char b[sizeof(\"\\\"\")]; };",
+		"// only a comment",
+	} {
+		err := NewTypes(api).Define(code)
+		if err == nil || strings.Contains(err.Error(), "unexpected command") {
+			t.Errorf("defining %q was not rejected - %v", code, err)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package radareutil
+
+import (
+	"fmt"
+	"strings"
+)
+
+type XrefType string
+
+func (o XrefType) String() string {
+	return string(o)
+}
+
+const (
+	XrefUnknown XrefType = "unknown"
+	XrefCode    XrefType = "code"
+	XrefCall    XrefType = "call"
+	XrefData    XrefType = "data"
+	XrefString  XrefType = "string"
+)
+
+func parseXrefType(str string) XrefType {
+	switch strings.ToUpper(str) {
+	case "CODE", "C":
+		return XrefCode
+	case "CALL":
+		return XrefCall
+	case "DATA", "D":
+		return XrefData
+	case "STRING", "STRN", "STRI", "S":
+		return XrefString
+	default:
+		return XrefUnknown
+	}
+}
+
+// Xref is a reference from one address to another.
+type Xref struct {
+	From   uint64
+	To     uint64
+	Type   XrefType
+	Opcode string
+
+	// FunctionAddr and FunctionName identify the function
+	// containing From, if any.
+	FunctionAddr uint64
+	FunctionName string
+
+	// RefName is the name of the flag at To, if any.
+	RefName string
+}
+
+type rawXref struct {
+	From     uint64 `json:"from"`
+	To       uint64 `json:"to"`
+	Type     string `json:"type"`
+	Opcode   string `json:"opcode"`
+	FcnAddr  uint64 `json:"fcn_addr"`
+	FcnName  string `json:"fcn_name"`
+	RefName  string `json:"refname"`
+	FlagName string `json:"name"`
+}
+
+// XrefsTo returns the references to addr ('axtj').
+func XrefsTo(api Api, addr uint64) ([]Xref, error) {
+	var raws []rawXref
+	err := api.ExecuteToJson(fmt.Sprintf("axtj @ 0x%x", addr), &raws)
+	if err != nil {
+		return nil, err
+	}
+
+	xrefs := make([]Xref, len(raws))
+	for i, raw := range raws {
+		xrefs[i] = Xref{
+			From:         raw.From,
+			To:           addr,
+			Type:         parseXrefType(raw.Type),
+			Opcode:       raw.Opcode,
+			FunctionAddr: raw.FcnAddr,
+			FunctionName: raw.FcnName,
+			RefName:      raw.RefName,
+		}
+	}
+
+	return xrefs, nil
+}
+
+// XrefsFrom returns the references made by the instruction at addr ('axfj').
+func XrefsFrom(api Api, addr uint64) ([]Xref, error) {
+	var raws []rawXref
+	err := api.ExecuteToJson(fmt.Sprintf("axfj @ 0x%x", addr), &raws)
+	if err != nil {
+		return nil, err
+	}
+
+	xrefs := make([]Xref, len(raws))
+	for i, raw := range raws {
+		xrefs[i] = Xref{
+			From:    raw.From,
+			To:      raw.To,
+			Type:    parseXrefType(raw.Type),
+			Opcode:  raw.Opcode,
+			RefName: raw.FlagName,
+		}
+	}
+
+	return xrefs, nil
+}
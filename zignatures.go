@@ -0,0 +1,236 @@
+package radareutil
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	// zignatureFlagPrefixVar is the eval variable containing the
+	// prefix of the flags created by a zignature search.
+	zignatureFlagPrefixVar = "zign.prefix"
+)
+
+var (
+	zignatureFlagSuffixRegex = regexp.MustCompile(`_\d+$`)
+)
+
+// ZignatureGraph contains the control flow graph metrics of
+// a zignature.
+type ZignatureGraph struct {
+	Complexity  int    `json:"cc"`
+	BasicBlocks int    `json:"nbbs"`
+	Edges       int    `json:"edges"`
+	Ebbs        int    `json:"ebbs"`
+	BbSum       uint64 `json:"bbsum"`
+}
+
+// Zignature is a function signature as reported by 'zj'.
+type Zignature struct {
+	Name  string `json:"name"`
+	Bytes string `json:"bytes"`
+	Mask  string `json:"mask"`
+
+	// Addr is the address of the function the zignature was
+	// generated from.
+	Addr  uint64          `json:"addr"`
+	Graph *ZignatureGraph `json:"graph"`
+	Refs  []string        `json:"refs"`
+	Types []string        `json:"types"`
+}
+
+// ZignatureMatch is a function that matched a zignature during a search.
+type ZignatureMatch struct {
+	Addr uint64
+
+	// Zignature is the name of the zignature that matched.
+	Zignature string
+
+	// Kind is the part of the zignature that matched, such as
+	// "bytes", "graph", "offset", "refs", or "types".
+	Kind string
+
+	// Flag is the flag radare2 created for the match.
+	Flag string
+}
+
+// Zignatures manages radare2 zignatures, which identify functions
+// such as those from a known library build.
+type Zignatures struct {
+	api Api
+}
+
+// Generate generates a zignature for the function at addr ('zaf').
+// If name is empty, the zignature is named after the function.
+func (o *Zignatures) Generate(addr uint64, name string) error {
+	command := "zaf"
+	if name != "" {
+		err := validateAnnotationName(name)
+		if err != nil {
+			return err
+		}
+
+		function, err := functionNameAt(o.api, addr)
+		if err != nil {
+			return err
+		}
+
+		command = fmt.Sprintf("zaf %s %s", function, name)
+	}
+
+	_, err := o.api.Execute(fmt.Sprintf("%s @ 0x%x", command, addr))
+	return err
+}
+
+// GenerateAll generates zignatures for every analyzed function ('zg').
+func (o *Zignatures) GenerateAll() error {
+	_, err := o.api.Execute("zg")
+	return err
+}
+
+// List returns the zignatures in the currently selected zignspace ('zj').
+func (o *Zignatures) List() ([]Zignature, error) {
+	var zignatures []Zignature
+	err := o.api.ExecuteToJson("zj", &zignatures)
+	if err != nil {
+		return nil, err
+	}
+
+	return zignatures, nil
+}
+
+// Delete deletes the named zignature ('z-').
+func (o *Zignatures) Delete(name string) error {
+	err := validateAnnotationName(name)
+	if err != nil {
+		return err
+	}
+
+	_, err = o.api.Execute("z-" + name)
+	return err
+}
+
+// Save saves the zignatures to an sdb file ('zos').
+func (o *Zignatures) Save(filePath string) error {
+	_, err := o.api.Execute(quoteCommand("zos " + filePath))
+	return err
+}
+
+// Load loads zignatures from an sdb file ('zo').
+func (o *Zignatures) Load(filePath string) error {
+	_, err := o.api.Execute(quoteCommand("zo " + filePath))
+	return err
+}
+
+// SelectSpace selects the named zignspace, creating it if it does not
+// exist ('zs'). An empty name selects all zignspaces.
+func (o *Zignatures) SelectSpace(name string) error {
+	if name == "" {
+		_, err := o.api.Execute("zs *")
+		return err
+	}
+
+	err := validateAnnotationName(name)
+	if err != nil {
+		return err
+	}
+
+	_, err = o.api.Execute("zs " + name)
+	return err
+}
+
+// Search searches for functions matching the loaded zignatures ('z/').
+// Flags from previous searches are deleted before the search.
+func (o *Zignatures) Search() ([]ZignatureMatch, error) {
+	prefix, err := NewEval(o.api).Get(zignatureFlagPrefixVar)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix = strings.TrimSpace(prefix)
+	if prefix == "" {
+		return nil, fmt.Errorf("eval variable '%s' is empty", zignatureFlagPrefixVar)
+	}
+
+	err = validateAnnotationName(prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = o.api.Execute(quoteCommand("f-" + prefix + ".*"))
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = o.api.Execute("z/")
+	if err != nil {
+		return nil, err
+	}
+
+	flags, err := AllFlags(o.api)
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []ZignatureMatch
+	for _, flag := range flags {
+		match, ok := parseZignatureFlag(prefix, flag)
+		if ok {
+			matches = append(matches, match)
+		}
+	}
+
+	sort.Slice(matches, func(i int, j int) bool {
+		if matches[i].Addr == matches[j].Addr {
+			return matches[i].Flag < matches[j].Flag
+		}
+		return matches[i].Addr < matches[j].Addr
+	})
+
+	return matches, nil
+}
+
+// NewZignatures returns a Zignatures for the provided Api.
+func NewZignatures(api Api) *Zignatures {
+	return &Zignatures{
+		api: api,
+	}
+}
+
+// parseZignatureFlag parses a flag created by a zignature search, which
+// is named "<prefix>.<kind>.<zignature>_<n>".
+func parseZignatureFlag(prefix string, flag Flag) (ZignatureMatch, bool) {
+	if !strings.HasPrefix(flag.Name, prefix+".") {
+		return ZignatureMatch{}, false
+	}
+
+	parts := strings.SplitN(strings.TrimPrefix(flag.Name, prefix+"."), ".", 2)
+	if len(parts) != 2 {
+		return ZignatureMatch{}, false
+	}
+
+	return ZignatureMatch{
+		Addr:      flag.Addr,
+		Zignature: zignatureFlagSuffixRegex.ReplaceAllString(parts[1], ""),
+		Kind:      parts[0],
+		Flag:      flag.Name,
+	}, true
+}
+
+// functionNameAt returns the name of the function at addr.
+func functionNameAt(api Api, addr uint64) (string, error) {
+	names, err := FunctionNames(api)
+	if err != nil {
+		return "", err
+	}
+
+	for _, name := range names {
+		if name.Addr == addr {
+			return name.Name, nil
+		}
+	}
+
+	return "", fmt.Errorf("no function at 0x%x", addr)
+}